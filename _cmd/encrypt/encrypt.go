@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/yowcow/goromdb/encfile"
+)
+
+func main() {
+	var keyFile string
+	var keyID string
+	var inFile string
+	var outFile string
+
+	flag.StringVar(&keyFile, "key-file", "", "read keys from")
+	flag.StringVar(&keyID, "key-id", "", "id of key to encrypt with")
+	flag.StringVar(&inFile, "input-from", "data/sample-boltdb.db", "read file to encrypt from")
+	flag.StringVar(&outFile, "output-to", "data/sample-boltdb.db.enc", "write encrypted file to")
+	flag.Parse()
+
+	encryptFile(keyFile, keyID, inFile, outFile)
+}
+
+func encryptFile(keyFile, keyID, inFile, outFile string) {
+	keyring, err := encfile.ReadKeyFile(keyFile)
+	if err != nil {
+		panic(err)
+	}
+	key, ok := keyring[keyID]
+	if !ok {
+		panic("key '" + keyID + "' not found in key file")
+	}
+
+	fi, err := os.Open(inFile)
+	if err != nil {
+		panic(err)
+	}
+	defer fi.Close()
+
+	fo, err := os.Create(outFile)
+	if err != nil {
+		panic(err)
+	}
+	defer fo.Close()
+
+	w, err := encfile.NewWriter(fo, keyID, key)
+	if err != nil {
+		panic(err)
+	}
+	if _, err = io.Copy(w, fi); err != nil {
+		panic(err)
+	}
+	if err = w.Close(); err != nil {
+		panic(err)
+	}
+}
@@ -6,39 +6,68 @@ import (
 	"io/ioutil"
 
 	"github.com/ajiyoshi-vg/goberkeleydb/bdb"
+	"github.com/yowcow/goromdb/storage/nskey"
 )
 
 // Data represents a key-value data
 type Data map[string]string
 
+// NSData represents a namespaced Data
+type NSData map[string]Data
+
 func main() {
 	var jsonFile string
 	var dbFile string
+	var namespaced bool
+	var nsEncoding string
 
 	flag.StringVar(&jsonFile, "input-from", "data/sample-data.json", "read JSON from")
 	flag.StringVar(&dbFile, "output-to", "data/sample-bdb.db", "write database to")
+	flag.BoolVar(&namespaced, "namespaced", false, "whether or not JSON is namespaced")
+	flag.StringVar(&nsEncoding, "ns-encoding", "length", "namespace key encoding: concat, length, separator=<separator> (for namespaced)")
 	flag.Parse()
 
-	writeDB(jsonFile, dbFile)
+	writeDB(readData(jsonFile, namespaced, nsEncoding), dbFile)
 }
 
-func writeDB(jsonFile, dbFile string) {
-	var data Data
-
+func readData(jsonFile string, namespaced bool, nsEncoding string) Data {
 	b, err := ioutil.ReadFile(jsonFile)
-
 	if err != nil {
 		panic(err)
 	}
 
-	err = json.Unmarshal(b, &data)
+	if !namespaced {
+		var data Data
+		if err = json.Unmarshal(b, &data); err != nil {
+			panic(err)
+		}
+		return data
+	}
 
+	enc, err := nskey.Parse(nsEncoding)
 	if err != nil {
 		panic(err)
 	}
+	var nsdata NSData
+	if err = json.Unmarshal(b, &nsdata); err != nil {
+		panic(err)
+	}
 
-	db, err := bdb.OpenBDB(bdb.NoEnv, bdb.NoTxn, dbFile, nil, bdb.BTree, bdb.DbCreate, 0)
+	data := make(Data)
+	for ns, nsd := range nsdata {
+		for k, v := range nsd {
+			fullKey, err := enc.Encode([]byte(ns), []byte(k))
+			if err != nil {
+				panic(err)
+			}
+			data[string(fullKey)] = v
+		}
+	}
+	return data
+}
 
+func writeDB(data Data, dbFile string) {
+	db, err := bdb.OpenBDB(bdb.NoEnv, bdb.NoTxn, dbFile, nil, bdb.BTree, bdb.DbCreate, 0)
 	if err != nil {
 		panic(err)
 	}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 
 	"github.com/boltdb/bolt"
+	"github.com/yowcow/goromdb/storage/bloom"
 )
 
 // Data represents a key-value data
@@ -15,13 +16,18 @@ func main() {
 	var jsonFile string
 	var dbFile string
 	var dbBucket string
+	var bloomFPRate float64
 
 	flag.StringVar(&jsonFile, "input-from", "data/sample-data.json", "read JSON from")
 	flag.StringVar(&dbFile, "output-to", "data/sample-boltdb.db", "write database to")
 	flag.StringVar(&dbBucket, "output-bucket", "goromdb", "bucket name to put data")
+	flag.Float64Var(&bloomFPRate, "bloom-fp-rate", 0, "false positive rate of bloom filter to write into <output-to>.bloom (0 disables)")
 	flag.Parse()
 
 	writeDB(jsonFile, dbFile, dbBucket)
+	if bloomFPRate > 0 {
+		writeBloomFilter(jsonFile, dbFile, dbBucket, bloomFPRate)
+	}
 }
 
 func writeDB(jsonFile, dbFile, dbBucket string) {
@@ -41,6 +47,7 @@ func writeDB(jsonFile, dbFile, dbBucket string) {
 	if err != nil {
 		panic(err)
 	}
+	defer db.Close()
 
 	db.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucket([]byte(dbBucket))
@@ -58,3 +65,32 @@ func writeDB(jsonFile, dbFile, dbBucket string) {
 		})
 	}
 }
+
+func writeBloomFilter(jsonFile, dbFile, dbBucket string, fpRate float64) {
+	var data Data
+
+	b, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		panic(err)
+	}
+
+	filter := bloom.New(len(data), fpRate)
+	for k := range data {
+		filter.Add([]byte(k))
+	}
+
+	sum, err := bloom.FileSum(dbFile)
+	if err != nil {
+		panic(err)
+	}
+	filter.SetScope(bloom.Scope{Sum: sum, Bucket: dbBucket})
+
+	if err = filter.WriteFile(dbFile + bloom.Suffix); err != nil {
+		panic(err)
+	}
+}
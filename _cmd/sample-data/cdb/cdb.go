@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+
+	"github.com/yowcow/goromdb/storage/cdbstorage"
+	"github.com/yowcow/goromdb/storage/nskey"
+)
+
+// Data represents a key-value data
+type Data map[string]string
+
+// NSData represents a namespaced Data
+type NSData map[string]Data
+
+func main() {
+	var jsonFile string
+	var dbFile string
+	var namespaced bool
+	var nsEncoding string
+
+	flag.StringVar(&jsonFile, "input-from", "data/sample-data.json", "read JSON from")
+	flag.StringVar(&dbFile, "output-to", "data/sample-cdb.db", "write database to")
+	flag.BoolVar(&namespaced, "namespaced", false, "whether or not JSON is namespaced")
+	flag.StringVar(&nsEncoding, "ns-encoding", "length", "namespace key encoding: concat, length, separator=<separator> (for namespaced)")
+	flag.Parse()
+
+	writeDB(readData(jsonFile, namespaced, nsEncoding), dbFile)
+}
+
+func readData(jsonFile string, namespaced bool, nsEncoding string) Data {
+	b, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		panic(err)
+	}
+
+	if !namespaced {
+		var data Data
+		if err = json.Unmarshal(b, &data); err != nil {
+			panic(err)
+		}
+		return data
+	}
+
+	enc, err := nskey.Parse(nsEncoding)
+	if err != nil {
+		panic(err)
+	}
+	var nsdata NSData
+	if err = json.Unmarshal(b, &nsdata); err != nil {
+		panic(err)
+	}
+
+	data := make(Data)
+	for ns, nsd := range nsdata {
+		for k, v := range nsd {
+			fullKey, err := enc.Encode([]byte(ns), []byte(k))
+			if err != nil {
+				panic(err)
+			}
+			data[string(fullKey)] = v
+		}
+	}
+	return data
+}
+
+func writeDB(data Data, dbFile string) {
+	w, err := cdbstorage.CreateWriter(dbFile)
+	if err != nil {
+		panic(err)
+	}
+
+	for k, v := range data {
+		if err = w.Put([]byte(k), []byte(v)); err != nil {
+			panic(err)
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/yowcow/goromdb/storage/nskey"
+)
+
+// Data represents a key-value data
+type Data map[string]string
+
+// NSData represents a namespaced Data
+type NSData map[string]Data
+
+func main() {
+	var jsonFile string
+	var dbDir string
+	var namespaced bool
+	var separator string
+
+	flag.StringVar(&jsonFile, "input-from", "data/sample-data.json", "read JSON from")
+	flag.StringVar(&dbDir, "output-to", "data/sample-leveldb.db", "write database directory to")
+	flag.BoolVar(&namespaced, "namespaced", false, "whether or not JSON is namespaced")
+	flag.StringVar(&separator, "ns-separator", "", "separator between namespace and key (for namespaced)")
+	flag.Parse()
+
+	data := readData(jsonFile, namespaced, separator)
+	writeDB(dbDir, data)
+}
+
+func readData(jsonFile string, namespaced bool, separator string) Data {
+	b, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		panic(err)
+	}
+
+	if !namespaced {
+		var data Data
+		if err = json.Unmarshal(b, &data); err != nil {
+			panic(err)
+		}
+		return data
+	}
+
+	var nsdata NSData
+	if err = json.Unmarshal(b, &nsdata); err != nil {
+		panic(err)
+	}
+
+	enc := nskey.Separator(separator)
+	data := make(Data)
+	for ns, nsd := range nsdata {
+		for k, v := range nsd {
+			fullKey, err := enc.Encode([]byte(ns), []byte(k))
+			if err != nil {
+				panic(err)
+			}
+			data[string(fullKey)] = v
+		}
+	}
+	return data
+}
+
+func writeDB(dbDir string, data Data) {
+	if err := os.RemoveAll(dbDir); err != nil {
+		panic(err)
+	}
+
+	db, err := leveldb.OpenFile(dbDir, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	batch := new(leveldb.Batch)
+	for k, v := range data {
+		batch.Put([]byte(k), []byte(v))
+	}
+	if err = db.Write(batch, nil); err != nil {
+		panic(err)
+	}
+
+	if err = db.Close(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+
+	"github.com/boltdb/bolt"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/memcdstorage"
+)
+
+// Data represents a key-value data
+type Data map[string]string
+
+func main() {
+	var jsonFile string
+	var dbFile string
+	var dbBucket string
+	var flags uint
+
+	flag.StringVar(&jsonFile, "input-from", "data/sample-data.json", "read JSON from")
+	flag.StringVar(&dbFile, "output-to", "data/sample-memcachedb-boltdb.db", "write database to")
+	flag.StringVar(&dbBucket, "output-bucket", "goromdb", "bucket name to put data")
+	flag.UintVar(&flags, "flags", 0, "memcached flags to store along with values")
+	flag.Parse()
+
+	writeDB(jsonFile, dbFile, dbBucket, uint32(flags))
+}
+
+func writeDB(jsonFile, dbFile, dbBucket string, flags uint32) {
+	var data Data
+
+	b, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		panic(err)
+	}
+
+	db, err := bolt.Open(dbFile, 0644, nil)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(dbBucket))
+		if err != nil {
+			return err
+		}
+
+		for k, v := range data {
+			buf := new(bytes.Buffer)
+			item := &storage.Item{Value: []byte(v), Flags: flags}
+			if err := memcdstorage.SerializeItem(buf, []byte(k), item); err != nil {
+				return err
+			}
+			if err := b.Put([]byte(k), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -3,30 +3,37 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/boltdb/bolt"
+	"github.com/yowcow/goromdb/storage/bloom"
 )
 
-// Data represents a key-value data
-type Data map[string]string
-
-// NSData represents a namespaced Data
-type NSData map[string]Data
+// NSData represents buckets of key-values, where a value being an object is a nested bucket
+type NSData map[string]map[string]interface{}
 
 func main() {
 	var jsonFile string
 	var dbFile string
-	var dbBucket string
+	var nsSeparator string
+	var bloomFPRate float64
 
 	flag.StringVar(&jsonFile, "input-from", "data/sample-ns-data.json", "read JSON from")
 	flag.StringVar(&dbFile, "output-to", "data/sample-ns-boltdb.db", "write database to")
+	flag.StringVar(&nsSeparator, "ns-separator", "", "join names of nested buckets into a namespace with, in bloom filter (must be the same as UseBucketPath of storage, or empty without it)")
+	flag.Float64Var(&bloomFPRate, "bloom-fp-rate", 0, "false positive rate of bloom filter to write into <output-to>.bloom (0 disables)")
 	flag.Parse()
 
-	writeDB(jsonFile, dbFile, dbBucket)
+	nsdata := readJSON(jsonFile)
+	writeDB(nsdata, dbFile)
+	if bloomFPRate > 0 {
+		writeBloomFilter(nsdata, dbFile, nsSeparator, bloomFPRate)
+	}
 }
 
-func writeDB(jsonFile, dbFile, dbBucket string) {
+func readJSON(jsonFile string) NSData {
 	var nsdata NSData
 
 	b, err := ioutil.ReadFile(jsonFile)
@@ -38,29 +45,114 @@ func writeDB(jsonFile, dbFile, dbBucket string) {
 	if err != nil {
 		panic(err)
 	}
+	return nsdata
+}
 
+func writeDB(nsdata NSData, dbFile string) {
 	db, err := bolt.Open(dbFile, 0644, nil)
 	if err != nil {
 		panic(err)
 	}
+	defer db.Close()
 
-	for bucket, data := range nsdata {
-		// create buckets
-		db.Update(func(tx *bolt.Tx) error {
-			_, err := tx.CreateBucket([]byte(bucket))
+	err = db.Update(func(tx *bolt.Tx) error {
+		for bucket, data := range nsdata {
+			b, err := tx.CreateBucket([]byte(bucket))
 			if err != nil {
-				panic(err)
+				return err
+			}
+			if err = putData(b, data); err != nil {
+				return err
 			}
-			return nil
-		})
-
-		// insert keys
-		for k, v := range data {
-			db.Update(func(tx *bolt.Tx) error {
-				b := tx.Bucket([]byte(bucket))
-				err := b.Put([]byte(k), []byte(v))
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// putData puts key-values into bucket, and creates a nested bucket for each object value
+func putData(b *bolt.Bucket, data map[string]interface{}) error {
+	for k, v := range data {
+		switch v := v.(type) {
+		case string:
+			if err := b.Put([]byte(k), []byte(v)); err != nil {
 				return err
-			})
+			}
+		case map[string]interface{}:
+			nested, err := b.CreateBucket([]byte(k))
+			if err != nil {
+				return err
+			}
+			if err = putData(nested, v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("expected a string or an object for key '%s' but got %v", k, v)
+		}
+	}
+	return nil
+}
+
+func writeBloomFilter(nsdata NSData, dbFile, nsSeparator string, fpRate float64) {
+	keys := make(map[string][]string)
+	for bucket, data := range nsdata {
+		if err := checkBucketName(bucket, nsSeparator); err != nil {
+			panic(err)
+		}
+		if err := collectKeys(keys, bucket, nsSeparator, data); err != nil {
+			panic(err)
 		}
 	}
+
+	n := 0
+	for _, ks := range keys {
+		n += len(ks)
+	}
+
+	filter := bloom.New(n, fpRate)
+	for ns, ks := range keys {
+		for _, k := range ks {
+			filter.Add(bloom.NSKey([]byte(ns), []byte(k)))
+		}
+	}
+
+	sum, err := bloom.FileSum(dbFile)
+	if err != nil {
+		panic(err)
+	}
+	filter.SetScope(bloom.Scope{Sum: sum, Separator: nsSeparator})
+
+	if err := filter.WriteFile(dbFile + bloom.Suffix); err != nil {
+		panic(err)
+	}
+}
+
+// collectKeys collects keys of values by namespace, joining names of nested buckets with separator
+func collectKeys(keys map[string][]string, ns, separator string, data map[string]interface{}) error {
+	for k, v := range data {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if separator == "" {
+				return fmt.Errorf("expected -ns-separator for nested bucket '%s' in '%s'", k, ns)
+			}
+			if err := checkBucketName(k, separator); err != nil {
+				return err
+			}
+			if err := collectKeys(keys, ns+separator+k, separator, nested); err != nil {
+				return err
+			}
+		} else {
+			keys[ns] = append(keys[ns], k)
+		}
+	}
+	return nil
+}
+
+// checkBucketName fails if a bucket name contains separator, which storage can't reach by a path
+func checkBucketName(name, separator string) error {
+	if separator != "" && strings.Contains(name, separator) {
+		return fmt.Errorf("bucket name '%s' contains separator '%s'", name, separator)
+	}
+	return nil
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Data represents a key-value data
+type Data map[string]string
+
+// NSData represents a namespaced Data
+type NSData map[string]Data
+
+func main() {
+	var jsonFile string
+	var dbFile string
+	var namespaced bool
+
+	flag.StringVar(&jsonFile, "input-from", "data/sample-data.json", "read JSON from")
+	flag.StringVar(&dbFile, "output-to", "data/sample-sqlite.db", "write database to")
+	flag.BoolVar(&namespaced, "namespaced", false, "whether or not JSON is namespaced (writes ns column)")
+	flag.Parse()
+
+	writeDB(jsonFile, dbFile, namespaced)
+}
+
+func writeDB(jsonFile, dbFile string, namespaced bool) {
+	b, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var nsdata NSData
+	if namespaced {
+		err = json.Unmarshal(b, &nsdata)
+	} else {
+		var data Data
+		err = json.Unmarshal(b, &data)
+		nsdata = NSData{"": data}
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if err = os.RemoveAll(dbFile); err != nil {
+		panic(err)
+	}
+
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	create := "CREATE TABLE goromdb (key TEXT PRIMARY KEY, value BLOB)"
+	insert := "INSERT INTO goromdb (key, value) VALUES (?, ?)"
+	if namespaced {
+		create = "CREATE TABLE goromdb (ns TEXT, key TEXT, value BLOB, PRIMARY KEY (ns, key))"
+		insert = "INSERT INTO goromdb (ns, key, value) VALUES (?, ?, ?)"
+	}
+	if _, err = db.Exec(create); err != nil {
+		panic(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		panic(err)
+	}
+	for ns, data := range nsdata {
+		for k, v := range data {
+			if namespaced {
+				_, err = tx.Exec(insert, ns, k, []byte(v))
+			} else {
+				_, err = tx.Exec(insert, k, []byte(v))
+			}
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/storage"
+)
+
+// adminMaxKeys defines the max number of keys listed by an admin command at once
+const adminMaxKeys = 1000
+
+// argsError represents an error in arguments to a command
+type argsError struct {
+	error
+}
+
+func adminUsageError(usage string) error {
+	return &argsError{fmt.Errorf("usage: admin %s", usage)}
+}
+
+// writeError writes an error line, telling whether client or server is wrong
+func writeError(w io.Writer, err error) {
+	if _, ok := err.(*argsError); ok {
+		fmt.Fprintf(w, "CLIENT_ERROR %s\r\n", err.Error())
+	} else {
+		fmt.Fprintf(w, "SERVER_ERROR %s\r\n", err.Error())
+	}
+}
+
+// runAdmin runs an admin command with given arguments against handler, and writes its reply lines followed by "END",
+// or an error line.
+// Keys and namespaces in replies and arguments are escaped by escapeKey, so that any byte in them never breaks a line.
+//
+//	admin count [<prefix>]                            replies "COUNT <n>"
+//	admin keys <limit> [<prefix> [<start>]]           replies "KEY <key>" for each key, and "NEXT <key>" to start next page at
+//	admin namespaces                                  replies "NS <namespace>" for each namespace
+//	admin count-ns <ns> [<prefix>]                    replies "COUNT <n>"
+//	admin keys-ns <ns> <limit> [<prefix> [<start>]]   replies as keys
+//	admin stats                                       replies "STAT <name> <value>" for each statistic, like cache hits
+func runAdmin(w io.Writer, args [][]byte, h handler.Handler) {
+	lines, err := execAdmin(args, h)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s\r\n", line)
+	}
+	fmt.Fprint(w, "END\r\n")
+}
+
+func execAdmin(args [][]byte, h handler.Handler) ([]string, error) {
+	if len(args) == 0 {
+		return nil, adminUsageError("count|keys|namespaces|count-ns|keys-ns|stats ...")
+	}
+	cmd := string(args[0])
+	args, err := unescapeArgs(args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cmd == "count" && len(args) <= 1:
+		n, err := storage.Count(h, optionalArg(args, 0))
+		return countLines(n, err)
+	case cmd == "count":
+		return nil, adminUsageError("count [<prefix>]")
+	case cmd == "keys" && len(args) >= 1 && len(args) <= 3:
+		return keysLines(args, func(prefix, start []byte, limit int) ([][]byte, error) {
+			return storage.Keys(h, prefix, start, limit)
+		})
+	case cmd == "keys":
+		return nil, adminUsageError("keys <limit> [<prefix> [<start>]]")
+	case cmd == "namespaces" && len(args) == 0:
+		names, err := storage.Namespaces(h)
+		if err != nil {
+			return nil, err
+		}
+		lines := make([]string, len(names))
+		for i, ns := range names {
+			lines[i] = "NS " + escapeKey(ns)
+		}
+		return lines, nil
+	case cmd == "namespaces":
+		return nil, adminUsageError("namespaces")
+	case cmd == "count-ns" && len(args) >= 1 && len(args) <= 2:
+		n, err := storage.CountNS(h, args[0], optionalArg(args, 1))
+		return countLines(n, err)
+	case cmd == "count-ns":
+		return nil, adminUsageError("count-ns <ns> [<prefix>]")
+	case cmd == "keys-ns" && len(args) >= 2 && len(args) <= 4:
+		ns := args[0]
+		return keysLines(args[1:], func(prefix, start []byte, limit int) ([][]byte, error) {
+			return storage.KeysNS(h, ns, prefix, start, limit)
+		})
+	case cmd == "keys-ns":
+		return nil, adminUsageError("keys-ns <ns> <limit> [<prefix> [<start>]]")
+	case cmd == "stats" && len(args) == 0:
+		r, ok := h.(storage.StatsReporter)
+		if !ok {
+			return nil, fmt.Errorf("handler reports no stats")
+		}
+		var lines []string
+		r.ReportStats(func(name string, value uint64) {
+			lines = append(lines, fmt.Sprintf("STAT %s %d", name, value))
+		})
+		return lines, nil
+	case cmd == "stats":
+		return nil, adminUsageError("stats")
+	default:
+		return nil, &argsError{fmt.Errorf("unknown admin command: %s", cmd)}
+	}
+}
+
+// parseLimit parses a positive limit, and caps it at max
+func parseLimit(arg []byte, max int) (int, error) {
+	limit, err := strconv.Atoi(string(arg))
+	if err != nil || limit < 1 {
+		return 0, &argsError{fmt.Errorf("invalid limit: %s", string(arg))}
+	}
+	if limit > max {
+		return max, nil
+	}
+	return limit, nil
+}
+
+func optionalArg(args [][]byte, i int) []byte {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func countLines(n int, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []string{"COUNT " + strconv.Itoa(n)}, nil
+}
+
+// keysLines lists keys with args of limit, and optional prefix and start.
+// A key more than limit is looked up to tell where next page starts.
+func keysLines(args [][]byte, keys func(prefix, start []byte, limit int) ([][]byte, error)) ([]string, error) {
+	limit, err := parseLimit(args[0], adminMaxKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := keys(optionalArg(args, 1), optionalArg(args, 2), limit+1)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for i, k := range found {
+		if i == limit {
+			lines = append(lines, "NEXT "+escapeKey(k))
+			break
+		}
+		lines = append(lines, "KEY "+escapeKey(k))
+	}
+	return lines, nil
+}
+
+// escapeKey escapes a byte other than printable ASCII, a space, or "%" in key as "%XX", so that key fits in an argument
+func escapeKey(key []byte) string {
+	var b strings.Builder
+	for _, c := range key {
+		if c <= ' ' || c >= 0x7f || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// unescapeArgs unescapes arguments escaped by escapeKey
+func unescapeArgs(args [][]byte) ([][]byte, error) {
+	unescaped := make([][]byte, len(args))
+	for i, arg := range args {
+		s, err := url.PathUnescape(string(arg))
+		if err != nil {
+			return nil, &argsError{fmt.Errorf("invalid escape in argument: %s", string(arg))}
+		}
+		unescaped[i] = []byte(s)
+	}
+	return unescaped, nil
+}
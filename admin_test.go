@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/handler/simplehandler"
+	"github.com/yowcow/goromdb/protocol/memcachedprotocol"
+	"github.com/yowcow/goromdb/storage/cachestorage"
+	"github.com/yowcow/goromdb/storage/cdbstorage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func splitArgs(line string) [][]byte {
+	return bytes.Split([]byte(line), []byte(" "))
+}
+
+func TestRunAdmin(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	h := simplehandler.New(jsonstorage.New(false), logger)
+	if err := h.Load("_data/sample-data.json"); err != nil {
+		t.Fatal(err)
+	}
+	nsh := simplehandler.NewNS(jsonstorage.NewNS(false), logger)
+	if err := nsh.Load("_data/sample-ns-data.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		args     string
+		expected string
+		subtest  string
+	}
+	cases := []Case{
+		{"count", "COUNT 5\r\nEND\r\n", "count all keys"},
+		{"count f", "COUNT 2\r\nEND\r\n", "count keys having prefix"},
+		{"keys 10", "KEY bar\r\nKEY buz\r\nKEY foo\r\nKEY fuga\r\nKEY hoge\r\nEND\r\n", "list all keys"},
+		{"keys 2", "KEY bar\r\nKEY buz\r\nNEXT foo\r\nEND\r\n", "list keys up to limit with next key"},
+		{"keys 2 f", "KEY foo\r\nKEY fuga\r\nEND\r\n", "list keys having prefix"},
+		{"keys 1 f fuga", "KEY fuga\r\nEND\r\n", "list keys having prefix starting at start"},
+		{"keys 0", "CLIENT_ERROR invalid limit: 0\r\n", "invalid limit fails"},
+		{"keys", "CLIENT_ERROR usage: admin keys <limit> [<prefix> [<start>]]\r\n", "missing limit fails"},
+		{"hoge", "CLIENT_ERROR unknown admin command: hoge\r\n", "unknown command fails"},
+		{"namespaces", "SERVER_ERROR storage is not iterable\r\n", "namespaces fails on handler without namespace"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			runAdmin(buf, splitArgs(c.args), h)
+
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+
+	nscases := []Case{
+		{"namespaces", "NS ns1\r\nNS ns2\r\nEND\r\n", "list namespaces"},
+		{"count-ns ns1", "COUNT 1\r\nEND\r\n", "count keys in namespace"},
+		{"keys-ns ns2 10", "KEY hoge\r\nEND\r\n", "list keys in namespace"},
+		{"keys-ns ns3 10", "SERVER_ERROR bucket not found error: ns3\r\n", "list keys in non-existing namespace fails"},
+	}
+
+	for _, c := range nscases {
+		t.Run(c.subtest, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			runAdmin(buf, splitArgs(c.args), nsh)
+
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+
+	t.Run("storage not iterable fails", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		runAdmin(buf, splitArgs("count"), simplehandler.New(cdbstorage.New(), logger))
+
+		assert.Equal(t, "SERVER_ERROR storage is not iterable\r\n", buf.String())
+	})
+}
+
+func TestRunAdminEscapesKeys(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.json")
+	ioutil.WriteFile(file, []byte(`{"a b":"1","c\r\nEND":"2","e%f":"3","gé":"4"}`), 0644)
+	h := simplehandler.New(jsonstorage.New(false), log.New(ioutil.Discard, "", 0))
+	if err := h.Load(file); err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		args     string
+		expected string
+		subtest  string
+	}
+	cases := []Case{
+		{"keys 10", "KEY a%20b\r\nKEY c%0D%0AEND\r\nKEY e%25f\r\nKEY g%C3%A9\r\nEND\r\n", "keys are escaped"},
+		{"keys 1", "KEY a%20b\r\nNEXT c%0D%0AEND\r\nEND\r\n", "next key is escaped"},
+		{"keys 10 c%0D%0A", "KEY c%0D%0AEND\r\nEND\r\n", "prefix is unescaped"},
+		{"keys 10 %zz", "CLIENT_ERROR invalid escape in argument: %zz\r\n", "invalid escape fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			runAdmin(buf, splitArgs(c.args), h)
+
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+}
+
+func TestRunAdminStats(t *testing.T) {
+	stg := cachestorage.New(jsonstorage.New(false), 1024, false)
+	h := simplehandler.New(stg, log.New(ioutil.Discard, "", 0))
+	if err := h.Load("_data/sample-data.json"); err != nil {
+		t.Fatal(err)
+	}
+	h.Get([]byte("hoge"))
+	h.Get([]byte("hoge"))
+
+	buf := new(bytes.Buffer)
+	runAdmin(buf, splitArgs("stats"), h)
+
+	expected := fmt.Sprintf(
+		"STAT generation 1\r\nSTAT memory_estimate %d\r\n"+
+			"STAT cache_hits 1\r\nSTAT cache_misses 1\r\nSTAT cache_items 1\r\nSTAT cache_bytes %d\r\nSTAT cache_evictions 0\r\n"+
+			"END\r\n",
+		stg.MemoryEstimate(), stg.Stats().Bytes,
+	)
+
+	assert.Equal(t, expected, buf.String())
+	assert.True(t, stg.MemoryEstimate() > uint64(stg.Stats().Bytes))
+
+	buf.Reset()
+	runAdmin(buf, splitArgs("stats hoge"), h)
+
+	assert.Equal(t, "CLIENT_ERROR usage: admin stats\r\n", buf.String())
+}
+
+func TestAdminIsRefusedUnlessEnabled(t *testing.T) {
+	h := simplehandler.New(jsonstorage.New(false), log.New(ioutil.Discard, "", 0))
+	h.Load("_data/sample-data.json")
+	route := func(key []byte) (handler.Handler, []byte) {
+		return h, key
+	}
+
+	type Case struct {
+		admin    bool
+		expected string
+		subtest  string
+	}
+	cases := []Case{
+		{false, "CLIENT_ERROR admin commands are not enabled on this listener\r\n", "admin is refused"},
+		{true, "COUNT 5\r\nEND\r\n", "admin is served"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			client, conn := net.Pipe()
+			defer client.Close()
+
+			cb := createCallback(memcachedprotocol.New(), route, c.admin)
+			go func() {
+				cb(conn, []byte("admin count"), log.New(ioutil.Discard, "", 0))
+				conn.Close()
+			}()
+			b, _ := ioutil.ReadAll(client)
+
+			assert.Equal(t, c.expected, string(b))
+		})
+	}
+}
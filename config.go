@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+)
+
+// defaultSeparator defines a separator between a database name and a key, like "users:key", in a config file
+const defaultSeparator = ":"
+
+// dbConfig represents configuration of a database, given by command line flags or by an entry in a config file
+type dbConfig struct {
+	Handler           string   `json:"handler"`
+	Storage           string   `json:"storage"`
+	Codec             string   `json:"codec"`
+	Compression       string   `json:"compression"`
+	File              string   `json:"file"`
+	WatchInterval     int      `json:"watch_interval"`
+	Checksum          string   `json:"checksum"`
+	Basedir           string   `json:"basedir"`
+	Gzipped           bool     `json:"gzipped"`
+	JSONValues        string   `json:"json_values"`
+	Bucket            string   `json:"bucket"`
+	BloomFPRate       float64  `json:"bloom_fp_rate"`
+	Shards            int      `json:"shards"`
+	ShardHash         string   `json:"shard_hash"`
+	SQLiteTable       string   `json:"sqlite_table"`
+	SQLiteKeyColumn   string   `json:"sqlite_key_column"`
+	SQLiteValueColumn string   `json:"sqlite_value_column"`
+	SQLiteNSColumn    string   `json:"sqlite_ns_column"`
+	KeyFile           string   `json:"key_file"`
+	CacheBytes        int      `json:"cache_bytes"`
+	CacheMisses       bool     `json:"cache_misses"`
+	Buckets           []string `json:"buckets"`
+	NSEncoding        string   `json:"ns_encoding"`
+	Fallback          []string `json:"fallback"`
+	FallbackDebug     bool     `json:"fallback_debug"`
+}
+
+// equal returns whether or not c has the same settings as other
+func (c dbConfig) equal(other dbConfig) bool {
+	return reflect.DeepEqual(c, other)
+}
+
+// handlerNames returns names of handlers serving a database, which are buckets if given, or otherwise the name of database
+func (c dbConfig) handlerNames(name string) []string {
+	if len(c.Buckets) > 0 {
+		return c.Buckets
+	}
+	return []string{name}
+}
+
+// config represents databases to serve.
+// A key like "users:key" is found as "key" in database "users", and any other key is found in the default database if any.
+type config struct {
+	Default   string
+	Separator string
+	Databases map[string]dbConfig
+}
+
+// singleConfig returns a config of a database given by command line flags, which serves all keys as they are
+func singleConfig(base dbConfig) *config {
+	base.Storage, base.Codec = resolveStorageAlias(base.Storage, base.Codec)
+	return &config{"default", "", map[string]dbConfig{"default": base}}
+}
+
+// readConfig reads a config file like:
+//
+//	{
+//	  "default": "users",
+//	  "separator": ":",
+//	  "databases": {
+//	    "users": {"storage": "boltdb", "bucket": "goromdb", "file": "/path/to/users.db", "basedir": "/path/to/store/users"},
+//	    "items": {"storage": "json", "file": "/path/to/items.json", "basedir": "/path/to/store/items", "watch_interval": 1000},
+//	    "shared": {"storage": "boltdb", "buckets": ["tags", "tenants"], "file": "/path/to/shared.db", "basedir": "/path/to/store/shared"},
+//	    "regional": {"handler": "fallback", "fallback": ["jp", "global"], "storage": "json", "file": "/path/to/regional.json", "basedir": "/path/to/store/regional"}
+//	  }
+//	}
+//
+// Settings not in an entry of a database are taken from base.
+// A database with buckets serves each bucket in one boltdb file as a database named by the bucket, like "tags:key".
+// A database with fallback handler finds a key like "regional:key" in namespaces of a namespaced storage in order.
+func readConfig(file string, base dbConfig) (*config, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Default   string                     `json:"default"`
+		Separator *string                    `json:"separator"`
+		Databases map[string]json.RawMessage `json:"databases"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config '%s': %s", file, err.Error())
+	}
+
+	cfg := &config{raw.Default, defaultSeparator, make(map[string]dbConfig)}
+	if raw.Separator != nil {
+		cfg.Separator = *raw.Separator
+	}
+	for name, entry := range raw.Databases {
+		c := base
+		if err := json.Unmarshal(entry, &c); err != nil {
+			return nil, fmt.Errorf("invalid config of database '%s': %s", name, err.Error())
+		}
+		c.Storage, c.Codec = resolveStorageAlias(c.Storage, c.Codec)
+		cfg.Databases[name] = c
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config '%s': %s", file, err.Error())
+	}
+	return cfg, nil
+}
+
+func (cfg *config) validate() error {
+	if len(cfg.Databases) == 0 {
+		return fmt.Errorf("no database to serve")
+	}
+	if cfg.Separator == "" {
+		return fmt.Errorf("separator must not be empty")
+	}
+	basedirs := make(map[string]string)
+	handlers := make(map[string]string)
+	for name, c := range cfg.Databases {
+		if name == "" {
+			return fmt.Errorf("database name must not be empty")
+		}
+		for _, h := range c.handlerNames(name) {
+			if h == "" {
+				return fmt.Errorf("bucket name of database '%s' must not be empty", name)
+			}
+			if other, ok := handlers[h]; ok {
+				return fmt.Errorf("databases '%s' and '%s' both serve '%s'", other, name, h)
+			}
+			handlers[h] = name
+		}
+		if c.WatchInterval <= 0 {
+			return fmt.Errorf("watch interval of database '%s' must be positive", name)
+		}
+		dir := filepath.Clean(c.Basedir)
+		if other, ok := basedirs[dir]; ok {
+			return fmt.Errorf("databases '%s' and '%s' share basedir '%s'", other, name, c.Basedir)
+		}
+		basedirs[dir] = name
+	}
+	if _, ok := handlers[cfg.Default]; cfg.Default != "" && !ok {
+		return fmt.Errorf("default database '%s' is not in databases", cfg.Default)
+	}
+	return nil
+}
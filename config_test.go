@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func writeConfig(dir, data string) string {
+	file := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+	return file
+}
+
+func TestReadConfig(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	base := dbConfig{Handler: "simple", Storage: "json", Codec: "none", WatchInterval: 5000, Bucket: "default"}
+	file := writeConfig(dir, `{
+		"default": "users",
+		"databases": {
+			"users": {"storage": "boltdb", "bucket": "goromdb", "file": "/path/to/users.db", "basedir": "/path/to/store/users"},
+			"items": {"storage": "memcachedb-bdb", "basedir": "/path/to/store/items", "watch_interval": 1000}
+		}
+	}`)
+
+	cfg, err := readConfig(file, base)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "users", cfg.Default)
+	assert.Equal(t, ":", cfg.Separator)
+	assert.Equal(t, dbConfig{
+		Handler: "simple", Storage: "boltdb", Codec: "none", File: "/path/to/users.db", WatchInterval: 5000,
+		Basedir: "/path/to/store/users", Bucket: "goromdb",
+	}, cfg.Databases["users"])
+	assert.Equal(t, dbConfig{
+		Handler: "simple", Storage: "bdb", Codec: "memcachedb", WatchInterval: 1000,
+		Basedir: "/path/to/store/items", Bucket: "default",
+	}, cfg.Databases["items"])
+}
+
+func TestReadInvalidConfig(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	base := dbConfig{WatchInterval: 5000}
+
+	type Case struct {
+		subtest string
+		input   string
+	}
+	cases := []Case{
+		{"broken JSON", `{`},
+		{"no database", `{"databases": {}}`},
+		{"unknown default", `{"default": "x", "databases": {"a": {"basedir": "/a"}}}`},
+		{"empty separator", `{"separator": "", "databases": {"a": {"basedir": "/a"}}}`},
+		{"non-positive watch interval", `{"databases": {"a": {"basedir": "/a", "watch_interval": 0}}}`},
+		{"shared basedir", `{"databases": {"a": {"basedir": "/a"}, "b": {"basedir": "/a/"}}}`},
+		{"invalid entry", `{"databases": {"a": {"shards": "x"}}}`},
+		{"empty bucket", `{"databases": {"a": {"basedir": "/a", "buckets": [""]}}}`},
+		{"bucket served twice", `{"databases": {"a": {"basedir": "/a", "buckets": ["x"]}, "x": {"basedir": "/x"}}}`},
+		{"default database having buckets", `{"default": "a", "databases": {"a": {"basedir": "/a", "buckets": ["x"]}}}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			_, err := readConfig(writeConfig(dir, c.input), base)
+
+			assert.NotNil(t, err)
+		})
+	}
+
+	_, err := readConfig(writeConfig(dir, `{"default": "x", "databases": {"a": {"basedir": "/a", "buckets": ["x", "y"]}}}`), base)
+
+	assert.Nil(t, err, "default database can be a bucket")
+
+	_, err = readConfig(filepath.Join(dir, "none.json"), base)
+
+	assert.NotNil(t, err)
+}
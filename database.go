@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/bloom"
+	"github.com/yowcow/goromdb/storage/boltstorage"
+	"github.com/yowcow/goromdb/storage/cachestorage"
+	"github.com/yowcow/goromdb/storage/nskey"
+	"github.com/yowcow/goromdb/storage/shardstorage"
+)
+
+// database represents handlers of a database, and a watcher feeding files to one of them
+type database struct {
+	config   dbConfig
+	handler  handler.Handler
+	handlers map[string]handler.Handler
+	storage  storage.Storage
+	loader   *loader.Loader
+	logger   *log.Logger
+	cancel   context.CancelFunc
+	done     <-chan bool
+}
+
+// newDatabase creates handlers of a database without loading or watching files, so that it fails before replacing another database.
+// Handlers are registered by names given by dbConfig.handlerNames.
+func newDatabase(name string, cfg dbConfig, logger *log.Logger) (*database, error) {
+	l, err := createLoader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := createWatcher(cfg, logger); err != nil {
+		return nil, err
+	}
+	if len(cfg.Buckets) > 0 {
+		return newBucketsDatabase(cfg, l, logger)
+	}
+	if cfg.Handler == "fallback" {
+		stg, err := createDBNSStorage(cfg)
+		if err != nil {
+			return nil, err
+		}
+		h, err := createFallbackHandler(cfg.Fallback, cfg.FallbackDebug, stg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return &database{cfg, h, map[string]handler.Handler{name: h}, stg, l, logger, nil, nil}, nil
+	}
+	stg, err := createDBStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	h, err := createHandler(cfg.Handler, stg, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &database{cfg, h, map[string]handler.Handler{name: h}, stg, l, logger, nil, nil}, nil
+}
+
+// newBucketsDatabase creates a handler of each bucket over a view of a boltdb storage, so that a file of all buckets is opened once.
+// Handler of the first bucket loads files, which swaps data of all buckets at once.
+func newBucketsDatabase(cfg dbConfig, l *loader.Loader, logger *log.Logger) (*database, error) {
+	if cfg.Storage != "boltdb" || cfg.Shards > 0 {
+		return nil, fmt.Errorf("buckets are served only from boltdb storage without shards")
+	}
+	shared := boltstorage.NewNS()
+	shared.UseBloomFilter(cfg.BloomFPRate)
+
+	handlers := make(map[string]handler.Handler)
+	for _, b := range cfg.Buckets {
+		stg, err := decorateStorage(cfg, shared.View(b))
+		if err != nil {
+			return nil, err
+		}
+		h, err := createHandler(cfg.Handler, stg, logger)
+		if err != nil {
+			return nil, err
+		}
+		handlers[b] = h
+	}
+	return &database{cfg, handlers[cfg.Buckets[0]], handlers, shared, l, logger, nil, nil}, nil
+}
+
+// start loads data already dropped in, and starts watching for new files
+func (db *database) start(ctx context.Context) {
+	if la, ok := db.handler.(interface{ LoadAny(*loader.Loader) bool }); ok {
+		// Serve data as soon as handler is registered
+		la.LoadAny(db.loader)
+	}
+	// Never fails, since it has succeeded in newDatabase
+	w, _ := createWatcher(db.config, db.logger)
+	ctx, db.cancel = context.WithCancel(ctx)
+	db.done = db.handler.Start(w.Start(ctx), db.loader)
+}
+
+// stop stops watching for new files, and waits for handler to finish.
+// Handler keeps serving data loaded so far.
+func (db *database) stop() {
+	db.cancel()
+	<-db.done
+}
+
+// close closes storage once reads in progress finish, which must be after handler is unregistered and stopped.
+// A read reaching handler after this fails, so databases closes a database replaced or removed after closeGrace.
+func (db *database) close(name string) {
+	if err := storage.Close(db.storage); err != nil {
+		db.logger.Printf("failed closing database '%s': %s", name, err.Error())
+	}
+}
+
+func createDBStorage(cfg dbConfig) (storage.Storage, error) {
+	newStorage := func() (storage.Storage, error) {
+		return createStorage(
+			cfg.Storage, cfg.Gzipped, cfg.JSONValues, cfg.Bucket, cfg.BloomFPRate,
+			cfg.SQLiteTable, cfg.SQLiteKeyColumn, cfg.SQLiteValueColumn,
+		)
+	}
+	stg, err := newStorage()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Shards > 0 {
+		hash, err := shardstorage.ParseHash(cfg.ShardHash)
+		if err != nil {
+			return nil, err
+		}
+		stg = shardstorage.New(cfg.Shards, hash, func() storage.Storage {
+			// Never fails, since it has succeeded once with the same arguments
+			s, _ := newStorage()
+			return s
+		})
+	}
+	return decorateStorage(cfg, stg)
+}
+
+// decorateStorage applies cache, codec and compression to stg
+func decorateStorage(cfg dbConfig, stg storage.Storage) (storage.Storage, error) {
+	if cfg.CacheBytes > 0 {
+		// Cache raw values, so that values in any codec are cached
+		stg = cachestorage.New(stg, cfg.CacheBytes, cfg.CacheMisses)
+	}
+	stg, err := applyCodec(cfg.Codec, stg)
+	if err != nil {
+		return nil, err
+	}
+	return applyCompression(cfg.Compression, stg)
+}
+
+// createDBNSStorage creates a namespaced storage of a database, for a handler finding keys in namespaces
+func createDBNSStorage(cfg dbConfig) (storage.NSStorage, error) {
+	if cfg.Shards > 0 {
+		return nil, fmt.Errorf("namespaced storage is not served from shards")
+	}
+	enc, err := nskey.Parse(cfg.NSEncoding)
+	if err != nil {
+		return nil, err
+	}
+	stg, err := createNSStorage(
+		cfg.Storage, cfg.Gzipped, cfg.JSONValues, cfg.BloomFPRate,
+		cfg.SQLiteTable, cfg.SQLiteNSColumn, cfg.SQLiteKeyColumn, cfg.SQLiteValueColumn, enc,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CacheBytes > 0 {
+		stg = cachestorage.NewNS(stg, cfg.CacheBytes, cfg.CacheMisses)
+	}
+	stg, err = applyNSCodec(cfg.Codec, stg, enc)
+	if err != nil {
+		return nil, err
+	}
+	return applyNSCompression(cfg.Compression, stg)
+}
+
+func createLoader(cfg dbConfig) (*loader.Loader, error) {
+	l, err := loader.New(cfg.Basedir, "data.db")
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BloomFPRate > 0 {
+		l.AddSidecar(bloom.Suffix)
+	}
+	if cfg.KeyFile != "" {
+		l.UseKeyFile(cfg.KeyFile)
+	}
+	if cfg.Gzipped {
+		// Storage decompresses a gzipped file on its own
+		l.KeepCompressed()
+	}
+	return l, nil
+}
+
+// closeGrace defines time to wait before closing a database replaced or removed,
+// so that a read having routed to its handler just before reaches storage and finishes
+const closeGrace = 5 * time.Second
+
+// routing represents how to find a database of a key, to be swapped as a whole
+type routing struct {
+	defaultName string
+	separator   []byte
+}
+
+// databases represents databases registered to a multiplexer by name, reconfigured without interrupting unchanged databases
+type databases struct {
+	mux        *handler.Multiplexer
+	opened     map[string]*database
+	routing    *atomic.Value
+	logger     *log.Logger
+	closeGrace time.Duration
+	closing    *sync.WaitGroup
+	flush      chan struct{}
+}
+
+func newDatabases(logger *log.Logger) *databases {
+	r := new(atomic.Value)
+	r.Store(&routing{})
+	return &databases{handler.NewMultiplexer(), make(map[string]*database), r, logger, closeGrace, new(sync.WaitGroup), make(chan struct{})}
+}
+
+// route returns a handler of a database to find a key in, and the key in the database.
+// Handler is nil if no database serves the key.
+func (d *databases) route(key []byte) (handler.Handler, []byte) {
+	r := d.routing.Load().(*routing)
+	if len(r.separator) > 0 {
+		if i := bytes.Index(key, r.separator); i > 0 {
+			if h, err := d.mux.GetHandler(string(key[:i])); err == nil {
+				return h, key[i+len(r.separator):]
+			}
+		}
+	}
+	if r.defaultName == "" {
+		return nil, key
+	}
+	h, _ := d.mux.GetHandler(r.defaultName)
+	return h, key
+}
+
+// reconfigure starts new databases, stops removed ones, and replaces changed ones with new ones having loaded data.
+// Unchanged databases are left as they are.
+// Handlers of new and changed databases are created before anything is changed, so that an invalid config changes nothing.
+func (d *databases) reconfigure(ctx context.Context, cfg *config) error {
+	pending := make(map[string]*database)
+	for name, c := range cfg.Databases {
+		if cur, ok := d.opened[name]; ok && cur.config.equal(c) {
+			continue
+		}
+		db, err := newDatabase(name, c, d.logger)
+		if err != nil {
+			return fmt.Errorf("invalid config of database '%s': %s", name, err.Error())
+		}
+		pending[name] = db
+	}
+
+	// Handler names of databases stopped, to be unregistered unless a database started serves them
+	var stale []string
+	for name, cur := range d.opened {
+		if _, ok := cfg.Databases[name]; !ok {
+			stale = append(stale, cur.config.handlerNames(name)...)
+			cur.stop()
+			d.closeLater(name, cur)
+			delete(d.opened, name)
+			d.logger.Printf("stopped database '%s'", name)
+		}
+	}
+	for name, db := range pending {
+		cur, ok := d.opened[name]
+		if ok {
+			// Never let watchers of old and new databases drop files into the same basedir at once
+			stale = append(stale, cur.config.handlerNames(name)...)
+			cur.stop()
+		}
+		db.start(ctx)
+		for hname, h := range db.handlers {
+			d.mux.SetHandler(hname, h)
+		}
+		d.opened[name] = db
+		if ok {
+			d.closeLater(name, cur)
+		}
+		c := db.config
+		d.logger.Printf("started database '%s' (storage: %s, codec: %s, file: %s)", name, c.Storage, c.Codec, c.File)
+	}
+	for _, hname := range stale {
+		if !d.serves(hname) {
+			d.mux.UnregisterHandler(hname)
+		}
+	}
+
+	d.routing.Store(&routing{cfg.Default, []byte(cfg.Separator)})
+	return nil
+}
+
+// serves returns whether or not a database opened has a handler of name
+func (d *databases) serves(name string) bool {
+	for _, db := range d.opened {
+		if _, ok := db.handlers[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// closeLater closes a database unregistered after closeGrace for reads having routed to it to finish, or at stopAll
+func (d *databases) closeLater(name string, db *database) {
+	d.closing.Add(1)
+	go func() {
+		defer d.closing.Done()
+		select {
+		case <-time.After(d.closeGrace):
+		case <-d.flush:
+		}
+		db.close(name)
+	}()
+}
+
+// stopAll stops and closes all databases, and closes databases unregistered before without waiting for closeGrace.
+// This must be after no connection reads anymore.
+func (d *databases) stopAll() {
+	for name, db := range d.opened {
+		for hname := range db.handlers {
+			d.mux.UnregisterHandler(hname)
+		}
+		db.stop()
+		db.close(name)
+		delete(d.opened, name)
+	}
+	close(d.flush)
+	d.closing.Wait()
+}
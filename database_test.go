@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+// createJSONDatabase returns a config of a json database having data already dropped into its basedir
+func createJSONDatabase(dir, name, data string) dbConfig {
+	basedir := filepath.Join(dir, name)
+	os.MkdirAll(filepath.Join(basedir, "data00"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(basedir, "data00", "data.db"), []byte(data), 0644); err != nil {
+		panic(err)
+	}
+	return dbConfig{
+		Handler: "simple", Storage: "json", Codec: "none", Compression: "none", JSONValues: "string", Checksum: "none",
+		File: filepath.Join(dir, name+".json"), WatchInterval: 100, Basedir: basedir,
+	}
+}
+
+func routeGet(d *databases, key string) string {
+	h, k := d.route([]byte(key))
+	if h == nil {
+		return "<no database>"
+	}
+	v, err := h.Get(k)
+	if err != nil {
+		return "<not found>"
+	}
+	return string(v)
+}
+
+func TestReconfigure(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	users := createJSONDatabase(dir, "users", `{"u1":"user1"}`)
+	items := createJSONDatabase(dir, "items", `{"i1":"item1"}`)
+	err := d.reconfigure(ctx, &config{"users", ":", map[string]dbConfig{"users": users, "items": items}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "user1", routeGet(d, "u1"))
+	assert.Equal(t, "user1", routeGet(d, "users:u1"))
+	assert.Equal(t, "item1", routeGet(d, "items:i1"))
+	assert.Equal(t, "<not found>", routeGet(d, "others:u1"))
+
+	itemsHandler, _ := d.mux.GetHandler("items")
+
+	// Remove users, add settings, change items, and make settings default
+	settings := createJSONDatabase(dir, "settings", `{"s1":"setting1"}`)
+	items.JSONValues = "any"
+	err = d.reconfigure(ctx, &config{"settings", "/", map[string]dbConfig{"items": items, "settings": settings}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "setting1", routeGet(d, "s1"))
+	assert.Equal(t, "item1", routeGet(d, "items/i1"))
+	assert.Equal(t, "<not found>", routeGet(d, "users/u1"))
+
+	newItemsHandler, _ := d.mux.GetHandler("items")
+
+	assert.NotEqual(t, itemsHandler, newItemsHandler)
+
+	// Leave unchanged databases as they are
+	err = d.reconfigure(ctx, &config{"", "/", map[string]dbConfig{"items": items, "settings": settings}})
+
+	assert.Nil(t, err)
+
+	h, _ := d.mux.GetHandler("items")
+
+	assert.Equal(t, newItemsHandler, h)
+	assert.Equal(t, "<no database>", routeGet(d, "s1"))
+}
+
+func TestReconfigureFailsWithoutChangingAnything(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	users := createJSONDatabase(dir, "users", `{"u1":"user1"}`)
+	d.reconfigure(ctx, &config{"users", ":", map[string]dbConfig{"users": users}})
+
+	items := createJSONDatabase(dir, "items", `{"i1":"item1"}`)
+	items.Codec = "unknown"
+	err := d.reconfigure(ctx, &config{"items", ":", map[string]dbConfig{"items": items}})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "user1", routeGet(d, "u1"))
+
+	_, err = d.mux.GetHandler("items")
+
+	assert.NotNil(t, err)
+}
+
+func TestSingleConfigRoutesKeysAsTheyAre(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	db := createJSONDatabase(dir, "db", `{"default:k":"v"}`)
+	err := d.reconfigure(ctx, singleConfig(db))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "v", routeGet(d, "default:k"))
+
+	var h handler.Handler
+	h, _ = d.route(nil)
+
+	assert.NotNil(t, h)
+}
+
+func TestGzippedDatabaseIsVerifiedAndDecompressedByStorage(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	db := createJSONDatabase(dir, "db", `{"k":"old"}`)
+	db.Gzipped = true
+	db.Checksum = "md5"
+	os.Remove(filepath.Join(db.Basedir, "data00", "data.db"))
+	err := d.reconfigure(ctx, singleConfig(db))
+
+	assert.Nil(t, err)
+
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	w.Write([]byte(`{"k":"new"}`))
+	w.Close()
+	sum := md5.Sum(buf.Bytes())
+	ioutil.WriteFile(db.File, buf.Bytes(), 0644)
+	ioutil.WriteFile(db.File+".md5", []byte(hex.EncodeToString(sum[:])), 0644)
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Equal(t, "new", routeGet(d, "k"))
+}
+
+func TestReconfigureFailsWithChecksumOfDirectory(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	db := createJSONDatabase(dir, "db", `{}`)
+	db.Storage = "leveldb"
+	db.Checksum = "md5"
+	err := d.reconfigure(context.Background(), singleConfig(db))
+
+	assert.NotNil(t, err)
+}
+
+func TestReconfigureClosesRemovedAndReplacedDatabases(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	d.closeGrace = 200 * time.Millisecond
+	defer d.stopAll()
+
+	b, err := ioutil.ReadFile("_data/store/sample-boltdb.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := createJSONDatabase(dir, "users", string(b))
+	users.Storage = "boltdb"
+	users.Bucket = "goromdb"
+	items := createJSONDatabase(dir, "items", `{"i1":"item1"}`)
+	d.reconfigure(ctx, &config{"users", ":", map[string]dbConfig{"users": users, "items": items}})
+	removed := d.opened["users"].handler
+
+	assert.Equal(t, "hoge!", routeGet(d, "hoge"))
+
+	items.WatchInterval = 200
+	err = d.reconfigure(ctx, &config{"items", ":", map[string]dbConfig{"items": items}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "item1", routeGet(d, "i1"))
+
+	// A read having routed to the removed database before still finishes
+	v, err := removed.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge!"), v)
+
+	time.Sleep(400 * time.Millisecond)
+	_, err = removed.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+	assert.False(t, storage.IsErrorKeyNotFound(err))
+}
+
+func TestReconfigureServesBucketsOfSharedFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	b, err := ioutil.ReadFile("_data/store/sample-ns-boltdb.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := createJSONDatabase(dir, "shared", string(b))
+	shared.Storage = "boltdb"
+	shared.Buckets = []string{"ns1", "ns2"}
+	err = d.reconfigure(ctx, &config{"ns1", ":", map[string]dbConfig{"shared": shared}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hoge1", routeGet(d, "hoge"))
+	assert.Equal(t, "hoge1", routeGet(d, "ns1:hoge"))
+	assert.Equal(t, "hoge2", routeGet(d, "ns2:hoge"))
+	assert.Equal(t, "<not found>", routeGet(d, "shared:hoge"))
+
+	// Handlers of all buckets are over the same generation of one storage
+	h1, _ := d.mux.GetHandler("ns1")
+	h2, _ := d.mux.GetHandler("ns2")
+	item1, _ := h1.GetItem([]byte("hoge"))
+	item2, _ := h2.GetItem([]byte("hoge"))
+
+	assert.Equal(t, item1.CAS, item2.CAS)
+
+	shared.Buckets = []string{"ns2"}
+	err = d.reconfigure(ctx, &config{"ns2", ":", map[string]dbConfig{"shared": shared}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hoge2", routeGet(d, "ns2:hoge"))
+
+	_, err = d.mux.GetHandler("ns1")
+
+	assert.NotNil(t, err)
+
+	items := createJSONDatabase(dir, "items", `{}`)
+	items.Buckets = []string{"ns3"}
+	err = d.reconfigure(ctx, &config{"ns2", ":", map[string]dbConfig{"shared": shared, "items": items}})
+
+	assert.NotNil(t, err)
+}
+
+func TestReconfigureServesFallbackOverNamespaces(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDatabases(log.New(ioutil.Discard, "", 0))
+	defer d.stopAll()
+
+	regional := createJSONDatabase(dir, "regional", `{"jp":{"hoge":"hoge-jp"},"global":{"hoge":"hoge-global","fuga":"fuga-global"}}`)
+	regional.Handler = "fallback"
+	regional.NSEncoding = "length"
+	err := d.reconfigure(ctx, &config{"regional", ":", map[string]dbConfig{"regional": regional}})
+
+	assert.NotNil(t, err)
+
+	regional.Fallback = []string{"jp", "global"}
+	err = d.reconfigure(ctx, &config{"regional", ":", map[string]dbConfig{"regional": regional}})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hoge-jp", routeGet(d, "regional:hoge"))
+	assert.Equal(t, "fuga-global", routeGet(d, "regional:fuga"))
+	assert.Equal(t, "fuga-global", routeGet(d, "fuga"))
+	assert.Equal(t, "<not found>", routeGet(d, "regional:piyo"))
+
+	regional.Shards = 2
+	err = d.reconfigure(ctx, &config{"regional", ":", map[string]dbConfig{"regional": regional}})
+
+	assert.NotNil(t, err)
+}
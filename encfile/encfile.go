@@ -0,0 +1,212 @@
+// Package encfile reads and writes files encrypted in AES-GCM in chunks.
+//
+// An encrypted file consists of a header and chunks:
+//
+//	header: "GRENC" | version (1) | key id length (1) | key id | nonce prefix (7)
+//	chunk:  AES-GCM sealed up to ChunkSize bytes of plaintext
+//
+// Nonce of each chunk is the nonce prefix, a big endian chunk counter (4), and 1 for the last chunk or 0 for others (1).
+// Header is authenticated as additional data of every chunk.
+package encfile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChunkSize defines the size of plaintext in a chunk
+const ChunkSize = 64 * 1024
+
+const (
+	version         = 1
+	noncePrefixSize = 7
+	nonceSize       = noncePrefixSize + 4 + 1
+	tagSize         = 16
+)
+
+// Magic defines magic bytes at the beginning of an encrypted file
+var Magic = []byte("GRENC")
+
+// IsEncrypted returns whether or not given leading bytes of a file are of an encrypted file
+func IsEncrypted(header []byte) bool {
+	return bytes.HasPrefix(header, Magic)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, nonceSize)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[nonceSize-1] = 1
+	}
+	return nonce
+}
+
+// Writer represents a writer encrypting into an encrypted file
+type Writer struct {
+	w       io.Writer
+	gcm     cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	started bool
+}
+
+// NewWriter creates a writer encrypting into w with a key identified by keyID.
+// Close must be called to write the last chunk.
+func NewWriter(w io.Writer, keyID string, key []byte) (*Writer, error) {
+	if len(keyID) == 0 || len(keyID) > 255 {
+		return nil, fmt.Errorf("key id must be 1 to 255 bytes")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{}, Magic...)
+	header = append(header, version, byte(len(keyID)))
+	header = append(header, keyID...)
+	header = append(header, prefix...)
+	return &Writer{w, gcm, header, prefix, 0, make([]byte, 0, ChunkSize), false}, nil
+}
+
+// Write encrypts p
+func (w *Writer) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// Keeping a full chunk until more data comes, since the last chunk is sealed differently
+		if len(w.buf) == ChunkSize {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):ChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+func (w *Writer) flush(last bool) error {
+	if !w.started {
+		if _, err := w.w.Write(w.header); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	sealed := w.gcm.Seal(nil, chunkNonce(w.prefix, w.counter, last), w.buf, w.header)
+	if _, err := w.w.Write(sealed); err != nil {
+		return err
+	}
+	w.counter++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the last chunk
+func (w *Writer) Close() error {
+	return w.flush(true)
+}
+
+// Reader represents a reader decrypting an encrypted file
+type Reader struct {
+	r       *bufio.Reader
+	gcm     cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	done    bool
+}
+
+// NewReader reads a header of an encrypted file from r, and creates a reader decrypting r with a key in keyring
+func NewReader(r io.Reader, keyring Keyring) (*Reader, error) {
+	br := bufio.NewReaderSize(r, ChunkSize+tagSize+1)
+
+	fixed := make([]byte, len(Magic)+2)
+	if _, err := io.ReadFull(br, fixed); err != nil {
+		return nil, fmt.Errorf("failed reading encrypted file header: %s", err.Error())
+	}
+	if !IsEncrypted(fixed) {
+		return nil, errors.New("not an encrypted file")
+	}
+	if fixed[len(Magic)] != version {
+		return nil, fmt.Errorf("unsupported encrypted file version: %d", fixed[len(Magic)])
+	}
+
+	rest := make([]byte, int(fixed[len(Magic)+1])+noncePrefixSize)
+	if _, err := io.ReadFull(br, rest); err != nil {
+		return nil, fmt.Errorf("failed reading encrypted file header: %s", err.Error())
+	}
+	keyID := string(rest[:len(rest)-noncePrefixSize])
+	key, ok := keyring[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key '%s' not found", keyID)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(fixed, rest...)
+	return &Reader{br, gcm, header, rest[len(rest)-noncePrefixSize:], 0, nil, false}, nil
+}
+
+// Read decrypts into p
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *Reader) readChunk() error {
+	sealed := make([]byte, ChunkSize+tagSize)
+	n, err := io.ReadFull(r.r, sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return errors.New("encrypted file is truncated")
+		}
+		return err
+	}
+	// The last chunk is followed by nothing
+	_, err = r.r.Peek(1)
+	last := err == io.EOF
+
+	plain, err := r.gcm.Open(nil, chunkNonce(r.prefix, r.counter, last), sealed[:n], r.header)
+	if err != nil {
+		return errors.New("failed decrypting encrypted file: corrupt, truncated or wrong key")
+	}
+	r.counter++
+	r.buf = plain
+	r.done = last
+	return nil
+}
@@ -0,0 +1,118 @@
+package encfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKeyring = Keyring{
+	"key1": bytes.Repeat([]byte{1}, 32),
+	"key2": bytes.Repeat([]byte{2}, 16),
+}
+
+func encrypt(plain []byte, keyID string) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, keyID, testKeyring[keyID])
+	if err != nil {
+		panic(err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(sealed []byte, keyring Keyring) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(sealed), keyring)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestWriteAndRead(t *testing.T) {
+	type Case struct {
+		size    int
+		keyID   string
+		subtest string
+	}
+	cases := []Case{
+		{0, "key1", "empty"},
+		{1, "key1", "1 byte"},
+		{ChunkSize, "key1", "exactly a chunk"},
+		{ChunkSize + 1, "key2", "a chunk and a byte"},
+		{ChunkSize*3 + 123, "key2", "multiple chunks"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			plain := make([]byte, c.size)
+			for i := range plain {
+				plain[i] = byte(i)
+			}
+			sealed := encrypt(plain, c.keyID)
+
+			assert.True(t, IsEncrypted(sealed))
+
+			actual, err := decrypt(sealed, testKeyring)
+
+			assert.Nil(t, err)
+			assert.Equal(t, plain, actual)
+		})
+	}
+}
+
+func TestNewWriterFails(t *testing.T) {
+	_, err := NewWriter(new(bytes.Buffer), "", testKeyring["key1"])
+
+	assert.NotNil(t, err)
+
+	_, err = NewWriter(new(bytes.Buffer), "key1", []byte("short"))
+
+	assert.NotNil(t, err)
+}
+
+func TestReadFails(t *testing.T) {
+	plain := bytes.Repeat([]byte("hoge"), ChunkSize/2)
+	sealed := encrypt(plain, "key1")
+	headerSize := len(Magic) + 2 + len("key1") + noncePrefixSize
+	chunk := ChunkSize + tagSize
+
+	tampered := append([]byte{}, sealed...)
+	tampered[headerSize+10] ^= 1
+
+	tamperedHeader := append([]byte{}, sealed...)
+	tamperedHeader[headerSize-1] ^= 1
+
+	type Case struct {
+		input   []byte
+		keyring Keyring
+		subtest string
+	}
+	cases := []Case{
+		{[]byte("hoge"), testKeyring, "not encrypted fails"},
+		{sealed[:headerSize-1], testKeyring, "truncated header fails"},
+		{sealed, Keyring{"key2": testKeyring["key2"]}, "unknown key id fails"},
+		{sealed, Keyring{"key1": testKeyring["key2"]}, "wrong key fails"},
+		{sealed[:headerSize+chunk], testKeyring, "truncated at chunk boundary fails"},
+		{sealed[:len(sealed)-1], testKeyring, "truncated last chunk fails"},
+		{append(append([]byte{}, sealed...), 0), testKeyring, "appended data fails"},
+		{tampered, testKeyring, "tampered chunk fails"},
+		{tamperedHeader, testKeyring, "tampered header fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			actual, err := decrypt(c.input, c.keyring)
+
+			assert.NotNil(t, err)
+			assert.NotEqual(t, plain, actual)
+		})
+	}
+}
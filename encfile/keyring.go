@@ -0,0 +1,50 @@
+package encfile
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Keyring represents keys by key id
+type Keyring map[string][]byte
+
+// ReadKeyFile reads a key file, having a key id and a hex encoded AES key (16, 24 or 32 bytes) in each line.
+// Empty lines and lines beginning with '#' are ignored.
+func ReadKeyFile(file string) (Keyring, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	keyring := make(Keyring)
+	scanner := bufio.NewScanner(fi)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected key id and key", n)
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key: %s", n, err.Error())
+		}
+		if l := len(key); l != 16 && l != 24 && l != 32 {
+			return nil, fmt.Errorf("line %d: invalid key size: %d", n, l)
+		}
+		if _, ok := keyring[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key id '%s'", n, fields[0])
+		}
+		keyring[fields[0]] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
@@ -0,0 +1,57 @@
+package encfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	key16 := strings.Repeat("01", 16)
+	key32 := strings.Repeat("ab", 32)
+
+	type Case struct {
+		input       string
+		expected    Keyring
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{
+			"# old key\nkey1 " + key16 + "\n\nkey2 " + key32 + "\n",
+			Keyring{"key1": []byte(strings.Repeat("\x01", 16)), "key2": []byte(strings.Repeat("\xab", 32))},
+			false,
+			"valid key file with comment and empty line",
+		},
+		{"key1\n", nil, true, "missing key fails"},
+		{"key1 hoge\n", nil, true, "non-hex key fails"},
+		{"key1 0102\n", nil, true, "invalid key size fails"},
+		{"key1 " + key16 + "\nkey1 " + key32 + "\n", nil, true, "duplicate key id fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			file := filepath.Join(dir, "keys")
+			if err := ioutil.WriteFile(file, []byte(c.input), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			keyring, err := ReadKeyFile(file)
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expected, keyring)
+		})
+	}
+
+	_, err := ReadKeyFile(filepath.Join(dir, "hoge"))
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,160 @@
+// Package fallbackhandler decorates a namespaced handler to find a key in an ordered list of namespaces.
+package fallbackhandler
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ handler.NSHandler      = (*Handler)(nil)
+	_ handler.NSItemHandler  = (*Handler)(nil)
+	_ handler.NSRangeHandler = (*Handler)(nil)
+	_ storage.NSIterable     = (*Handler)(nil)
+)
+
+// Handler represents a namespaced handler finding a key in fallback namespaces in order, and returning the first hit
+type Handler struct {
+	handler    handler.NSHandler
+	namespaces [][]byte
+	logger     *log.Logger
+	debug      bool
+}
+
+// New creates and returns a handler finding a key in namespaces in order, like "jp" and then "global"
+func New(h handler.NSHandler, namespaces []string, logger *log.Logger) *Handler {
+	nss := make([][]byte, len(namespaces))
+	for i, ns := range namespaces {
+		nss[i] = []byte(ns)
+	}
+	return &Handler{h, nss, logger, false}
+}
+
+// UseDebugLog makes handler log which namespace served each value
+func (h *Handler) UseDebugLog() {
+	h.debug = true
+}
+
+// Start starts underlying handler
+func (h *Handler) Start(filein <-chan string, l *loader.Loader) <-chan bool {
+	return h.handler.Start(filein, l)
+}
+
+// LoadAny loads a file found by loader into underlying handler if it can, and returns whether or not data is loaded
+func (h *Handler) LoadAny(l *loader.Loader) bool {
+	if la, ok := h.handler.(interface{ LoadAny(*loader.Loader) bool }); ok {
+		return la.LoadAny(l)
+	}
+	return false
+}
+
+// Load loads data into underlying handler
+func (h *Handler) Load(file string) error {
+	return h.handler.Load(file)
+}
+
+// Get finds a key in fallback namespaces, so that a key over protocol is served from the first namespace having it
+func (h *Handler) Get(key []byte) ([]byte, error) {
+	return h.GetNS(nil, key)
+}
+
+// GetItem finds a key in fallback namespaces, and returns the value with its metadata by underlying handler if any
+func (h *Handler) GetItem(key []byte) (*storage.Item, error) {
+	return h.GetItemNS(nil, key)
+}
+
+// GetNS finds a key in a given namespace first, and then in fallback namespaces
+func (h *Handler) GetNS(ns, key []byte) ([]byte, error) {
+	item, err := h.find(ns, key, h.getValue)
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+// GetItemNS finds a key in a given namespace first and then in fallback namespaces, and returns the value with its metadata.
+// Value, flags and CAS come from one read by underlying handler if it serves items, or otherwise only value is.
+func (h *Handler) GetItemNS(ns, key []byte) (*storage.Item, error) {
+	return h.find(ns, key, h.getItem)
+}
+
+func (h *Handler) getValue(ns, key []byte) (*storage.Item, error) {
+	v, err := h.handler.GetNS(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Item{Value: v}, nil
+}
+
+func (h *Handler) getItem(ns, key []byte) (*storage.Item, error) {
+	if ih, ok := h.handler.(handler.NSItemHandler); ok {
+		return ih.GetItemNS(ns, key)
+	}
+	return h.getValue(ns, key)
+}
+
+// find finds a key with get in ns if given and then in fallback namespaces, skipping namespaces not found.
+// Errors other than not-found are returned as they are.
+func (h *Handler) find(ns, key []byte, get func(ns, key []byte) (*storage.Item, error)) (*storage.Item, error) {
+	if ns != nil {
+		item, err := get(ns, key)
+		if err == nil {
+			h.logServed(ns, key)
+			return item, nil
+		}
+		if !isNotFound(err) {
+			return nil, err
+		}
+	}
+	for _, fallback := range h.namespaces {
+		if ns != nil && bytes.Equal(ns, fallback) {
+			continue
+		}
+		item, err := get(fallback, key)
+		if err == nil {
+			h.logServed(fallback, key)
+			return item, nil
+		}
+		if !isNotFound(err) {
+			return nil, err
+		}
+	}
+	return nil, storage.KeyNotFoundError(key)
+}
+
+func isNotFound(err error) bool {
+	return storage.IsErrorKeyNotFound(err) || storage.IsErrorBucketNotFound(err)
+}
+
+func (h *Handler) logServed(ns, key []byte) {
+	if h.debug {
+		h.logger.Printf("fallbackhandler served key '%s' from namespace '%s'", string(key), string(ns))
+	}
+}
+
+// Namespaces returns namespaces in underlying handler if it is iterable
+func (h *Handler) Namespaces() ([][]byte, error) {
+	return storage.Namespaces(h.handler)
+}
+
+// KeysNS finds keys in namespace in underlying handler if it is iterable
+func (h *Handler) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.KeysNS(h.handler, ns, prefix, start, limit)
+}
+
+// CountNS counts keys having prefix in namespace in underlying handler if it is iterable
+func (h *Handler) CountNS(ns, prefix []byte) (int, error) {
+	return storage.CountNS(h.handler, ns, prefix)
+}
+
+// RangeNS finds items in a range of keys in namespace in underlying handler if it supports range
+func (h *Handler) RangeNS(ns, from, to []byte, limit int, fn func([]byte, *storage.Item)) ([]byte, error) {
+	if r, ok := h.handler.(handler.NSRangeHandler); ok {
+		return r.RangeNS(ns, from, to, limit, fn)
+	}
+	return nil, storage.NotIterableError()
+}
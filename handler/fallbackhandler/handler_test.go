@@ -0,0 +1,114 @@
+package fallbackhandler
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/handler/simplehandler"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+)
+
+var sampleNSDataFile = "../../_data/store/sample-ns-data.json"
+
+func createHandler(namespaces []string) (*Handler, *bytes.Buffer) {
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+	h := New(simplehandler.NewNS(jsonstorage.NewNS(false), logger), namespaces, logger)
+	h.Load(sampleNSDataFile)
+	return h, logbuf
+}
+
+func TestGet(t *testing.T) {
+	type Case struct {
+		subtest       string
+		namespaces    []string
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{"first namespace serves", []string{"ns2", "ns1"}, []byte("hoge2"), false},
+		{"missing namespace falls back", []string{"ns3", "ns1"}, []byte("hoge1"), false},
+		{"all namespaces missing fails", []string{"ns3", "ns4"}, nil, true},
+		{"no namespaces fails", nil, nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			h, _ := createHandler(c.namespaces)
+
+			v, err := h.Get([]byte("hoge"))
+
+			assert.Equal(t, c.errorExpected, err != nil)
+			assert.Equal(t, c.expectedVal, v)
+
+			item, err := h.GetItem([]byte("hoge"))
+
+			assert.Equal(t, c.errorExpected, err != nil)
+			if !c.errorExpected {
+				assert.Equal(t, c.expectedVal, item.Value)
+				assert.Equal(t, uint64(1), item.CAS)
+			}
+		})
+	}
+}
+
+// itemHandler represents a handler serving an item with flags in namespace "ns1" only
+type itemHandler struct {
+	handler.NSHandler
+}
+
+func (h itemHandler) GetItemNS(ns, key []byte) (*storage.Item, error) {
+	if string(ns) != "ns1" {
+		return nil, storage.BucketNotFoundError(ns)
+	}
+	return &storage.Item{Value: []byte("hoge1"), Flags: 3, CAS: 7}, nil
+}
+
+func TestGetItemFromItemHandler(t *testing.T) {
+	h := New(itemHandler{}, []string{"ns2", "ns1"}, log.New(new(bytes.Buffer), "", 0))
+
+	item, err := h.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &storage.Item{Value: []byte("hoge1"), Flags: 3, CAS: 7}, item)
+
+	item, err = h.GetItemNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), item.Flags)
+}
+
+func TestGetNS(t *testing.T) {
+	h, _ := createHandler([]string{"ns3", "ns1"})
+
+	v, err := h.GetNS([]byte("ns2"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge2"), v)
+
+	v, err = h.GetNS([]byte("ns4"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge1"), v)
+
+	_, err = h.GetNS([]byte("ns2"), []byte("fuga"))
+
+	assert.NotNil(t, err)
+}
+
+func TestDebugLog(t *testing.T) {
+	h, logbuf := createHandler([]string{"ns3", "ns1"})
+
+	h.Get([]byte("hoge"))
+
+	assert.Equal(t, "", logbuf.String())
+
+	h.UseDebugLog()
+	h.Get([]byte("hoge"))
+
+	assert.Equal(t, "fallbackhandler served key 'hoge' from namespace 'ns1'\n", logbuf.String())
+}
@@ -0,0 +1,157 @@
+// Package grouphandler loads files of related datasets together, and switches handlers of all of them to the new files at once.
+package grouphandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/handler/simplehandler"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+)
+
+// ManifestFile defines the name of a file in a dropped-in directory, mapping names of members to their files
+const ManifestFile = "manifest.json"
+
+var (
+	_ storage.Storage = (*Group)(nil)
+)
+
+// Group represents a group of members loading files in a directory together.
+// Each member loads its file into its standby storage in background, and all members switch to their standby storages at once,
+// or none does if any member fails loading.
+type Group struct {
+	members []*Member
+	state   *atomic.Value
+	mux     *sync.RWMutex
+	handler *simplehandler.Handler
+}
+
+// state represents storages members serve, and the number of times members have switched, to be swapped together
+type state struct {
+	index      int
+	generation uint64
+}
+
+// New creates and returns a group
+func New(logger *log.Logger) *Group {
+	g := &Group{nil, new(atomic.Value), new(sync.RWMutex), nil}
+	g.handler = simplehandler.New(g, logger)
+	return g
+}
+
+// AddMember adds a member loading file in a dropped-in directory into two storages created by newStorage, and returns its handler.
+// This must be called before starting group.
+func (g *Group) AddMember(name, file string, newStorage func() storage.Storage) *Member {
+	m := &Member{g, name, file, [2]storage.Storage{newStorage(), newStorage()}}
+	g.members = append(g.members, m)
+	return m
+}
+
+// Start starts a goroutine loading directories from filein dropped in with l
+func (g *Group) Start(filein <-chan string, l *loader.Loader) <-chan bool {
+	return g.handler.Start(filein, l)
+}
+
+// Load loads files in a directory into standby storages of all members, and switches all members to them
+func (g *Group) Load(dir string) error {
+	files, err := g.memberFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	cur := g.getState()
+	next := &state{0, 1}
+	if cur != nil {
+		next = &state{1 - cur.index, cur.generation + 1}
+	}
+	for i, m := range g.members {
+		if err := m.sets[next.index].Load(files[i]); err != nil {
+			return fmt.Errorf("failed loading member '%s' from '%s': %s", m.name, files[i], err.Error())
+		}
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.state.Store(next)
+	return nil
+}
+
+// Get finds no key, since keys are served by members
+func (g *Group) Get(key []byte) ([]byte, error) {
+	return nil, storage.KeyNotFoundError(key)
+}
+
+// Generation returns the number of times members have switched to new files
+func (g *Group) Generation() uint64 {
+	if st := g.getState(); st != nil {
+		return st.generation
+	}
+	return 0
+}
+
+// memberFiles returns files of members in dir, which are mapped by a manifest if exists
+func (g *Group) memberFiles(dir string) ([]string, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("path '%s' is not a directory of member files", dir)
+	}
+
+	files := make([]string, len(g.members))
+	manifest, err := readManifest(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		for i, m := range g.members {
+			files[i] = filepath.Join(dir, m.file)
+		}
+		return files, nil
+	}
+
+	if len(manifest) != len(g.members) {
+		return nil, fmt.Errorf("manifest in '%s' has %d members but expected %d", dir, len(manifest), len(g.members))
+	}
+	for i, m := range g.members {
+		file, ok := manifest[m.name]
+		if !ok {
+			return nil, fmt.Errorf("manifest in '%s' has no file for member '%s'", dir, m.name)
+		}
+		// Never let a file in manifest point outside of dir
+		files[i] = filepath.Join(dir, filepath.Clean("/"+file))
+	}
+	return files, nil
+}
+
+// readManifest reads a manifest mapping names of members to their files, or returns nil if not exists
+func readManifest(file string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest '%s': %s", file, err.Error())
+	}
+	return manifest, nil
+}
+
+func (g *Group) getState() *state {
+	if ptr := g.state.Load(); ptr != nil {
+		return ptr.(*state)
+	}
+	return nil
+}
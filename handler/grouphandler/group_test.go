@@ -0,0 +1,164 @@
+package grouphandler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func newJSONStorage() storage.Storage {
+	return jsonstorage.New(false)
+}
+
+func createGroup() (*Group, *Member, *Member) {
+	g := New(log.New(new(bytes.Buffer), "", 0))
+	users := g.AddMember("users", "users.json", newJSONStorage)
+	settings := g.AddMember("settings", "settings.json", newJSONStorage)
+	return g, users, settings
+}
+
+// writeFiles writes files in a new directory, and returns the directory
+func writeFiles(files map[string]string) string {
+	dir := testutil.CreateTmpDir()
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			panic(err)
+		}
+	}
+	return dir
+}
+
+func assertServes(t *testing.T, m *Member, key, expected string, generation uint64) {
+	item, err := m.GetItem([]byte(key))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte(expected), item.Value)
+	assert.Equal(t, generation, item.CAS)
+}
+
+func TestLoadSwitchesAllMembers(t *testing.T) {
+	g, users, settings := createGroup()
+
+	_, err := users.Get([]byte("u1"))
+
+	assert.NotNil(t, err)
+
+	for i, v := range []string{"v1", "v2", "v3"} {
+		dir := writeFiles(map[string]string{
+			"users.json":    `{"u1":"user-` + v + `"}`,
+			"settings.json": `{"u1":"setting-` + v + `"}`,
+		})
+		err := g.Load(dir)
+		os.RemoveAll(dir)
+
+		assert.Nil(t, err)
+		assertServes(t, users, "u1", "user-"+v, uint64(i+1))
+		assertServes(t, settings, "u1", "setting-"+v, uint64(i+1))
+	}
+}
+
+func TestLoadWithManifest(t *testing.T) {
+	g, users, settings := createGroup()
+
+	dir := writeFiles(map[string]string{
+		"manifest.json":          `{"users":"users-20261019.json","settings":"../settings-20261019.json"}`,
+		"users-20261019.json":    `{"u1":"user"}`,
+		"settings-20261019.json": `{"u1":"setting"}`,
+	})
+	defer os.RemoveAll(dir)
+
+	err := g.Load(dir)
+
+	assert.Nil(t, err)
+	assertServes(t, users, "u1", "user", 1)
+	assertServes(t, settings, "u1", "setting", 1)
+}
+
+func TestLoadFailsWithoutSwitchingAnyMember(t *testing.T) {
+	type Case struct {
+		subtest string
+		files   map[string]string
+	}
+	cases := []Case{
+		{
+			"missing member file",
+			map[string]string{"users.json": `{"u1":"user-new"}`},
+		},
+		{
+			"broken member file",
+			map[string]string{"users.json": `{"u1":"user-new"}`, "settings.json": `{`},
+		},
+		{
+			"manifest missing member",
+			map[string]string{"manifest.json": `{"users":"users.json","other":"settings.json"}`, "users.json": `{}`, "settings.json": `{}`},
+		},
+		{
+			"manifest having extra member",
+			map[string]string{"manifest.json": `{"users":"users.json","settings":"settings.json","other":"x.json"}`},
+		},
+		{
+			"broken manifest",
+			map[string]string{"manifest.json": `[`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			g, users, settings := createGroup()
+			good := writeFiles(map[string]string{"users.json": `{"u1":"user"}`, "settings.json": `{"u1":"setting"}`})
+			defer os.RemoveAll(good)
+			assert.Nil(t, g.Load(good))
+
+			dir := writeFiles(c.files)
+			defer os.RemoveAll(dir)
+
+			err := g.Load(dir)
+
+			assert.NotNil(t, err)
+			assertServes(t, users, "u1", "user", 1)
+			assertServes(t, settings, "u1", "setting", 1)
+		})
+	}
+}
+
+func TestStart(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	g, users, settings := createGroup()
+	mux := handler.NewMultiplexer()
+	mux.RegisterHandler("users", users)
+	mux.RegisterHandler("settings", settings)
+
+	filein := make(chan string)
+	l, _ := loader.New(dir, "data")
+	h, _ := mux.GetHandler("users")
+	done := h.Start(filein, l)
+
+	for _, v := range []string{"v1", "v2"} {
+		incoming := writeFiles(map[string]string{
+			"users.json":    `{"u1":"user-` + v + `"}`,
+			"settings.json": `{"u1":"setting-` + v + `"}`,
+		})
+		filein <- incoming
+	}
+	close(filein)
+	<-done
+
+	h, _ = mux.GetHandler("settings")
+	v, err := h.Get([]byte("u1"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("setting-v2"), v)
+	assertServes(t, users, "u1", "user-v2", g.Generation())
+}
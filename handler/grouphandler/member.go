@@ -0,0 +1,68 @@
+package grouphandler
+
+import (
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ handler.Handler = (*Member)(nil)
+)
+
+// Member represents a handler serving a member of a group, like a handler registered to a multiplexer
+type Member struct {
+	group *Group
+	name  string
+	file  string
+	sets  [2]storage.Storage
+}
+
+// Start starts the group, since members are loaded only together.
+// Only one of members, or the group itself, needs to be started.
+func (m *Member) Start(filein <-chan string, l *loader.Loader) <-chan bool {
+	return m.group.Start(filein, l)
+}
+
+// Load loads files in a directory into all members of the group
+func (m *Member) Load(dir string) error {
+	return m.group.Load(dir)
+}
+
+// Get finds value by given key in the storage member serves, and returns the value
+func (m *Member) Get(key []byte) ([]byte, error) {
+	m.group.mux.RLock()
+	defer m.group.mux.RUnlock()
+
+	st := m.group.getState()
+	if st == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+	return m.sets[st.index].Get(key)
+}
+
+// GetItem finds value by given key, and returns the value with its metadata if storage serves any.
+// CAS of the item is the generation of the group, which is the same among members.
+func (m *Member) GetItem(key []byte) (*storage.Item, error) {
+	m.group.mux.RLock()
+	defer m.group.mux.RUnlock()
+
+	st := m.group.getState()
+	if st == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+	stg, cas := m.sets[st.index], st.generation
+	if s, ok := stg.(storage.ItemStorage); ok {
+		item, err := s.GetItem(key)
+		if err != nil {
+			return nil, err
+		}
+		item.CAS = cas
+		return item, nil
+	}
+	v, err := stg.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Item{Value: v, CAS: cas}, nil
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
 )
 
 // Handler defines an interface to a handler
@@ -9,6 +10,7 @@ type Handler interface {
 	Start(<-chan string, *loader.Loader) <-chan bool
 	Load(string) error
 	Get(key []byte) ([]byte, error)
+	GetItem(key []byte) (*storage.Item, error)
 }
 
 // NSHandler defines an interface to a handler with namespace support
@@ -16,3 +18,20 @@ type NSHandler interface {
 	Handler
 	GetNS(ns, key []byte) ([]byte, error)
 }
+
+// NSItemHandler defines an interface to a namespaced handler finding a value with its metadata in a namespace
+type NSItemHandler interface {
+	GetItemNS(ns, key []byte) (*storage.Item, error)
+}
+
+// RangeHandler defines an interface to a handler finding items in a range of keys page by page.
+// Range calls fn with up to limit items having keys from "from" to "to" inclusive (no upper bound if to is empty) in order,
+// and returns a key to start next page at if more items exist.
+type RangeHandler interface {
+	Range(from, to []byte, limit int, fn func(key []byte, item *storage.Item)) ([]byte, error)
+}
+
+// NSRangeHandler defines an interface to a handler finding items in a range of keys in a namespace page by page
+type NSRangeHandler interface {
+	RangeNS(ns, from, to []byte, limit int, fn func(key []byte, item *storage.Item)) ([]byte, error)
+}
@@ -2,15 +2,18 @@ package handler
 
 import (
 	"fmt"
+	"sync"
 )
 
 type handlerMap map[string]Handler
 type nsHandlerMap map[string]NSHandler
 
-// Multiplexer represents a handler multiplexer
+// Multiplexer represents a handler multiplexer.
+// Handlers can be registered, replaced, and unregistered while others are being served.
 type Multiplexer struct {
 	handlers   *handlerMap
 	nshandlers *nsHandlerMap
+	mux        *sync.RWMutex
 }
 
 // NewMultiplexer creates a Multiplexer
@@ -20,11 +23,15 @@ func NewMultiplexer() *Multiplexer {
 	return &Multiplexer{
 		handlers:   &handlers,
 		nshandlers: &nsHandlers,
+		mux:        new(sync.RWMutex),
 	}
 }
 
 // RegisterHandler registers a Handler to Multiplexer
 func (m *Multiplexer) RegisterHandler(name string, hdr Handler) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
 	if _, ok := (*m.handlers)[name]; ok {
 		return fmt.Errorf("handler with name '%s' already registered", name)
 	}
@@ -32,8 +39,31 @@ func (m *Multiplexer) RegisterHandler(name string, hdr Handler) error {
 	return nil
 }
 
+// SetHandler registers a Handler to Multiplexer, replacing a Handler with the same name if registered
+func (m *Multiplexer) SetHandler(name string, hdr Handler) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	(*m.handlers)[name] = hdr
+}
+
+// UnregisterHandler unregisters a Handler with given name from Multiplexer
+func (m *Multiplexer) UnregisterHandler(name string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, ok := (*m.handlers)[name]; !ok {
+		return fmt.Errorf("handler with name '%s' not registered", name)
+	}
+	delete(*m.handlers, name)
+	return nil
+}
+
 // RegisterNSHandler registers a NSHandler to Multiplexer
 func (m *Multiplexer) RegisterNSHandler(name string, hdr NSHandler) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
 	if _, ok := (*m.nshandlers)[name]; ok {
 		return fmt.Errorf("nshandler with name '%s' already registered", name)
 	}
@@ -43,6 +73,9 @@ func (m *Multiplexer) RegisterNSHandler(name string, hdr NSHandler) error {
 
 // GetHandler returns a Handler with given name
 func (m *Multiplexer) GetHandler(name string) (Handler, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
 	if hdr, ok := (*m.handlers)[name]; ok {
 		return hdr, nil
 	}
@@ -51,6 +84,9 @@ func (m *Multiplexer) GetHandler(name string) (Handler, error) {
 
 // GetNSHandler returns a NSHandler with given name
 func (m *Multiplexer) GetNSHandler(name string) (NSHandler, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
 	if hdr, ok := (*m.nshandlers)[name]; ok {
 		return hdr, nil
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
 )
 
 var (
@@ -21,6 +22,11 @@ func (h *testHandler) Get(k []byte) ([]byte, error) {
 	return []byte(fmt.Sprintf("get %s from %s", string(k), h.name)), nil
 }
 
+func (h *testHandler) GetItem(k []byte) (*storage.Item, error) {
+	v, err := h.Get(k)
+	return &storage.Item{Value: v}, err
+}
+
 func (h *testHandler) Load(file string) error {
 	return nil
 }
@@ -88,3 +94,33 @@ func TestGetNSHandler(t *testing.T) {
 	assert.Nil(t, hdr)
 	assert.NotNil(t, err)
 }
+
+func TestSetHandler(t *testing.T) {
+	m := NewMultiplexer()
+	m.SetHandler("h1", &testHandler{"h1"})
+	m.SetHandler("h1", &testHandler{"h2"})
+	hdr, err := m.GetHandler("h1")
+
+	assert.Nil(t, err)
+
+	v, _ := hdr.Get([]byte("k"))
+
+	assert.Equal(t, []byte("get k from h2"), v)
+}
+
+func TestUnregisterHandler(t *testing.T) {
+	m := NewMultiplexer()
+	_ = m.RegisterHandler("h1", &testHandler{"h1"})
+
+	err := m.UnregisterHandler("h1")
+
+	assert.Nil(t, err)
+
+	_, err = m.GetHandler("h1")
+
+	assert.NotNil(t, err)
+
+	err = m.UnregisterHandler("h1")
+
+	assert.NotNil(t, err)
+}
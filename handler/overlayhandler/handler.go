@@ -0,0 +1,210 @@
+// Package overlayhandler overlays handlers of small delta databases over a handler of a base database.
+package overlayhandler
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/handler/simplehandler"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ handler.Handler = (*Handler)(nil)
+	_ storage.Storage = (*layerStorage)(nil)
+)
+
+// Handler represents a handler finding a key in layers from the top down, and returning the first hit.
+// Each layer loads its own files into a standby storage, and all reads switch to it at once,
+// so that a read never waits for loading nor sees a layer half way through loading.
+// Reads hold a read lock across all layers read, and switching waits for them,
+// so that no read of an older snapshot remains when a layer loads into its standby storage again.
+type Handler struct {
+	layers     []*layer // from the bottom up
+	snapshot   *atomic.Value
+	tombstone  []byte
+	mux        *sync.RWMutex
+	generation uint64
+	logger     *log.Logger
+}
+
+// layer represents a handler with its own source of files, where the base layer at the bottom has none until started
+type layer struct {
+	index   int
+	handler *simplehandler.Handler
+	filein  <-chan string
+	loader  *loader.Loader
+}
+
+// snapshot represents storages served by layers from the bottom up, and the number of times any layer has been loaded.
+// A layer not loaded yet has nil storage.
+type snapshot struct {
+	storages   []storage.Storage
+	generation uint64
+}
+
+// layerStorage represents two storages of a layer, loading data into the standby one while the other is served
+type layerStorage struct {
+	owner   *Handler
+	layer   *layer
+	sets    [2]storage.Storage
+	standby int
+	mux     *sync.Mutex
+}
+
+// New creates and returns a handler with a base layer over storages created by newStorage, which loads files given at start
+func New(newStorage func() storage.Storage, logger *log.Logger) *Handler {
+	h := &Handler{nil, new(atomic.Value), nil, new(sync.RWMutex), 0, logger}
+	h.layers = []*layer{h.newLayer(newStorage, nil, nil)}
+	h.snapshot.Store(&snapshot{make([]storage.Storage, len(h.layers)), 0})
+	return h
+}
+
+// AddLayer adds a layer over storages created by newStorage on top of layers added before, which loads files from filein with l.
+// This must be called before starting handler.
+func (h *Handler) AddLayer(newStorage func() storage.Storage, filein <-chan string, l *loader.Loader) error {
+	if filein == nil || l == nil {
+		return fmt.Errorf("layer must have a source of files and a loader")
+	}
+	h.layers = append(h.layers, h.newLayer(newStorage, filein, l))
+	h.snapshot.Store(&snapshot{make([]storage.Storage, len(h.layers)), 0})
+	return nil
+}
+
+func (h *Handler) newLayer(newStorage func() storage.Storage, filein <-chan string, l *loader.Loader) *layer {
+	ly := &layer{len(h.layers), nil, filein, l}
+	s := &layerStorage{h, ly, [2]storage.Storage{newStorage(), newStorage()}, 0, new(sync.Mutex)}
+	ly.handler = simplehandler.New(s, h.logger)
+	return ly
+}
+
+// UseTombstone makes a value equal to marker in a layer delete the key from layers below
+func (h *Handler) UseTombstone(marker string) {
+	h.tombstone = []byte(marker)
+}
+
+// Start starts all layers, where the base layer loads files from filein with l.
+// Returned channel is closed after all layers finish.
+func (h *Handler) Start(filein <-chan string, l *loader.Loader) <-chan bool {
+	dones := make([]<-chan bool, len(h.layers))
+	for i, ly := range h.layers {
+		if i == 0 {
+			dones[i] = ly.handler.Start(filein, l)
+		} else {
+			dones[i] = ly.handler.Start(ly.filein, ly.loader)
+		}
+	}
+
+	done := make(chan bool)
+	go func() {
+		for _, d := range dones {
+			<-d
+		}
+		close(done)
+	}()
+	return done
+}
+
+// Load loads data into the base layer
+func (h *Handler) Load(file string) error {
+	return h.layers[0].handler.Load(file)
+}
+
+// swap makes a layer serve storage in a new snapshot, taking a new generation
+func (h *Handler) swap(ly *layer, stg storage.Storage) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	cur := h.snapshot.Load().(*snapshot)
+	storages := make([]storage.Storage, len(cur.storages))
+	copy(storages, cur.storages)
+	storages[ly.index] = stg
+	h.generation++
+	h.snapshot.Store(&snapshot{storages, h.generation})
+}
+
+// served returns storage served by a layer, or nil if not loaded yet
+func (h *Handler) served(ly *layer) storage.Storage {
+	return h.snapshot.Load().(*snapshot).storages[ly.index]
+}
+
+// Get finds a key in layers from the top down, and returns the value
+func (h *Handler) Get(key []byte) ([]byte, error) {
+	item, err := h.GetItem(key)
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+// GetItem finds a key in layers from the top down, and returns the value with its metadata.
+// CAS of the item is the number of times any layer has been loaded, taken together with layers read.
+func (h *Handler) GetItem(key []byte) (*storage.Item, error) {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	snap := h.snapshot.Load().(*snapshot)
+	for i := len(snap.storages) - 1; i >= 0; i-- {
+		stg := snap.storages[i]
+		if stg == nil {
+			continue
+		}
+		item, err := getItem(stg, key)
+		if err != nil {
+			if storage.IsErrorKeyNotFound(err) || storage.IsErrorBucketNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if h.tombstone != nil && bytes.Equal(item.Value, h.tombstone) {
+			return nil, storage.KeyNotFoundError(key)
+		}
+		item.CAS = snap.generation
+		return item, nil
+	}
+	return nil, storage.KeyNotFoundError(key)
+}
+
+// getItem finds value by given key, and returns the value with its metadata if storage serves any
+func getItem(stg storage.Storage, key []byte) (*storage.Item, error) {
+	if is, ok := stg.(storage.ItemStorage); ok {
+		return is.GetItem(key)
+	}
+	v, err := stg.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Item{Value: v}, nil
+}
+
+// Load loads data into the standby storage without blocking reads, and makes the layer serve it.
+// The standby storage was last served before the previous swap, which waited for all reads of it to finish.
+func (s *layerStorage) Load(file string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	stg := s.sets[s.standby]
+	if err := stg.Load(file); err != nil {
+		return err
+	}
+	s.owner.swap(s.layer, stg)
+	s.standby = 1 - s.standby
+	return nil
+}
+
+// Get finds a given key in the storage served by the layer
+func (s *layerStorage) Get(key []byte) ([]byte, error) {
+	s.owner.mux.RLock()
+	defer s.owner.mux.RUnlock()
+
+	stg := s.owner.served(s.layer)
+	if stg == nil {
+		return nil, storage.KeyNotFoundError(key)
+	}
+	return stg.Get(key)
+}
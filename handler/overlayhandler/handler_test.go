@@ -0,0 +1,287 @@
+package overlayhandler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+var sampleDataFile = "../../_data/store/sample-data.json"
+
+func writeDelta(file, data string) {
+	if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+}
+
+func newJSONStorage() storage.Storage {
+	return jsonstorage.New(false)
+}
+
+// addLayer adds a layer loading files from a channel never fed, to be loaded directly in tests
+func addLayer(h *Handler, dir string) {
+	l, err := loader.New(dir, "data.json")
+	if err != nil {
+		panic(err)
+	}
+	if err := h.AddLayer(newJSONStorage, make(chan string), l); err != nil {
+		panic(err)
+	}
+}
+
+func TestAddLayerFailsWithoutFiles(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	h := New(newJSONStorage, log.New(new(bytes.Buffer), "", 0))
+	l, _ := loader.New(dir, "data.json")
+
+	assert.NotNil(t, h.AddLayer(newJSONStorage, nil, l))
+	assert.NotNil(t, h.AddLayer(newJSONStorage, make(chan string), nil))
+	assert.Equal(t, 1, len(h.layers))
+}
+
+func TestGetItem(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logger := log.New(new(bytes.Buffer), "", 0)
+	h := New(newJSONStorage, logger)
+	addLayer(h, dir)
+	addLayer(h, dir)
+	h.UseTombstone("__deleted__")
+
+	delta1 := filepath.Join(dir, "delta1.json")
+	delta2 := filepath.Join(dir, "delta2.json")
+	writeDelta(delta1, `{"hoge":"hoge1","foo":"__deleted__","piyo":"piyo1"}`)
+	writeDelta(delta2, `{"hoge":"hoge2","foo":"foo2"}`)
+
+	assert.Nil(t, h.Load(sampleDataFile))
+	assert.Nil(t, h.layers[2].handler.Load(delta2))
+	assert.Nil(t, h.layers[1].handler.Load(delta1))
+
+	type Case struct {
+		subtest       string
+		key           string
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{"top layer overrides", "hoge", []byte("hoge2"), false},
+		{"top layer overrides tombstone", "foo", []byte("foo2"), false},
+		{"middle layer adds", "piyo", []byte("piyo1"), false},
+		{"base layer serves", "bar", []byte("bar!!!!"), false},
+		{"non-existing key fails", "nope", nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			item, err := h.GetItem([]byte(c.key))
+
+			assert.Equal(t, c.errorExpected, err != nil)
+			if !c.errorExpected {
+				assert.Equal(t, c.expectedVal, item.Value)
+				assert.Equal(t, uint64(3), item.CAS)
+			}
+		})
+	}
+}
+
+func TestTombstone(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logger := log.New(new(bytes.Buffer), "", 0)
+	h := New(newJSONStorage, logger)
+	addLayer(h, dir)
+
+	delta := filepath.Join(dir, "delta.json")
+	writeDelta(delta, `{"hoge":"__deleted__"}`)
+
+	h.Load(sampleDataFile)
+	h.layers[1].handler.Load(delta)
+
+	v, err := h.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("__deleted__"), v)
+
+	h.UseTombstone("__deleted__")
+	v, err = h.Get([]byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestStart(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logger := log.New(new(bytes.Buffer), "", 0)
+	h := New(newJSONStorage, logger)
+
+	deltaDir := testutil.CreateTmpDir()
+	defer os.RemoveAll(deltaDir)
+
+	deltain := make(chan string)
+	deltaLoader, _ := loader.New(deltaDir, "data.json")
+	h.AddLayer(newJSONStorage, deltain, deltaLoader)
+
+	baseDir := testutil.CreateTmpDir()
+	defer os.RemoveAll(baseDir)
+
+	basein := make(chan string)
+	baseLoader, _ := loader.New(baseDir, "data.json")
+	done := h.Start(basein, baseLoader)
+
+	base := filepath.Join(dir, "base.json")
+	testutil.CopyFile(base, sampleDataFile)
+	basein <- base
+
+	delta := filepath.Join(dir, "delta.json")
+	writeDelta(delta, `{"hoge":"hoge1"}`)
+	deltain <- delta
+
+	close(basein)
+	close(deltain)
+	<-done
+
+	v, err := h.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge1"), v)
+
+	v, err = h.Get([]byte("foo"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("foo!!!"), v)
+}
+
+// blockingStorage represents a storage blocking in Load until unblocked
+type blockingStorage struct {
+	storage.Storage
+	loading chan bool
+	unblock chan bool
+}
+
+func (s blockingStorage) Load(file string) error {
+	s.loading <- true
+	<-s.unblock
+	return s.Storage.Load(file)
+}
+
+func TestLoadNeverBlocksReads(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	loading := make(chan bool)
+	unblock := make(chan bool)
+	h := New(func() storage.Storage {
+		return blockingStorage{jsonstorage.New(false), loading, unblock}
+	}, log.New(new(bytes.Buffer), "", 0))
+	addLayer(h, dir)
+
+	delta := filepath.Join(dir, "delta.json")
+	writeDelta(delta, `{"hoge":"hoge1"}`)
+	h.layers[1].handler.Load(delta)
+
+	done := make(chan error)
+	go func() {
+		done <- h.Load(sampleDataFile)
+	}()
+	<-loading
+
+	item, err := h.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge1"), item.Value)
+	assert.Equal(t, uint64(1), item.CAS)
+
+	_, err = h.Get([]byte("foo"))
+
+	assert.NotNil(t, err)
+
+	unblock <- true
+
+	assert.Nil(t, <-done)
+
+	item, err = h.GetItem([]byte("foo"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("foo!!!"), item.Value)
+	assert.Equal(t, uint64(2), item.CAS)
+}
+
+// readingStorage represents a storage blocking in Get until unblocked
+type readingStorage struct {
+	storage.Storage
+	reading chan bool
+	unblock chan bool
+}
+
+func (s readingStorage) Get(key []byte) ([]byte, error) {
+	s.reading <- true
+	<-s.unblock
+	return s.Storage.Get(key)
+}
+
+func TestSwapWaitsForReadsOfOlderSnapshot(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	reading := make(chan bool)
+	unblock := make(chan bool)
+	h := New(func() storage.Storage {
+		return readingStorage{jsonstorage.New(false), reading, unblock}
+	}, log.New(new(bytes.Buffer), "", 0))
+	addLayer(h, dir)
+	h.Load(sampleDataFile)
+
+	type result struct {
+		item *storage.Item
+		err  error
+	}
+	read := make(chan result)
+	go func() {
+		item, err := h.GetItem([]byte("foo"))
+		read <- result{item, err}
+	}()
+	<-reading
+
+	delta := filepath.Join(dir, "delta.json")
+	writeDelta(delta, `{"foo":"foo1"}`)
+	done := make(chan error)
+	go func() {
+		done <- h.layers[1].handler.Load(delta)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected layer not to switch while a read of older snapshot is in progress")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unblock <- true
+	r := <-read
+
+	assert.Nil(t, r.err)
+	assert.Equal(t, []byte("foo!!!"), r.item.Value)
+	assert.Equal(t, uint64(1), r.item.CAS)
+	assert.Nil(t, <-done)
+
+	item, err := h.GetItem([]byte("foo"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("foo1"), item.Value)
+	assert.Equal(t, uint64(2), item.CAS)
+}
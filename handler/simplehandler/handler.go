@@ -8,7 +8,10 @@ import (
 )
 
 var (
-	_ handler.Handler = (*Handler)(nil)
+	_ handler.Handler       = (*Handler)(nil)
+	_ handler.RangeHandler  = (*Handler)(nil)
+	_ storage.Iterable      = (*Handler)(nil)
+	_ storage.StatsReporter = (*Handler)(nil)
 )
 
 // Handler represents a simple handler
@@ -18,10 +21,55 @@ type Handler struct {
 
 // New creates and returns a handler
 func New(stg storage.Storage, logger *log.Logger) *Handler {
-	return &Handler{StorageHandler{stg, logger}}
+	return &Handler{StorageHandler{stg, logger, new(uint64)}}
 }
 
 // Get finds value by given key, and returns the value
 func (h *Handler) Get(key []byte) ([]byte, error) {
 	return h.storage.Get(key)
 }
+
+// GetItem finds value by given key, and returns the value with its metadata if storage serves any.
+// CAS of the item is the generation of loaded data the value is read from, which stays the same until next load.
+func (h *Handler) GetItem(key []byte) (*storage.Item, error) {
+	var item *storage.Item
+	cas, err := h.read(func() error {
+		var err error
+		item, err = h.getItem(key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	item.CAS = cas
+	return item, nil
+}
+
+func (h *Handler) getItem(key []byte) (*storage.Item, error) {
+	if s, ok := h.storage.(storage.ItemStorage); ok {
+		return s.GetItem(key)
+	}
+	v, err := h.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Item{Value: v}, nil
+}
+
+// Keys finds keys in storage if it is iterable, and returns up to limit keys having prefix starting at start
+func (h *Handler) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.Keys(h.storage, prefix, start, limit)
+}
+
+// Count counts keys having prefix in storage if it is iterable
+func (h *Handler) Count(prefix []byte) (int, error) {
+	return storage.Count(h.storage, prefix)
+}
+
+// Range finds items in a range of keys in storage if it supports range, and calls fn with up to limit items.
+// Returns a key to start next page at if more items exist.
+func (h *Handler) Range(from, to []byte, limit int, fn func([]byte, *storage.Item)) ([]byte, error) {
+	return h.page(limit, fn, func(f func([]byte, *storage.Item) bool) error {
+		return storage.Range(h.storage, from, to, f)
+	})
+}
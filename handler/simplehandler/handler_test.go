@@ -6,14 +6,19 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/boltstorage"
 	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/storage/memcdstorage"
 	"github.com/yowcow/goromdb/testutil"
 )
 
 var sampleDataFile = "../../_data/store/sample-data.json"
+var sampleMemcachedbFile = "../../_data/store/sample-memcachedb-boltdb.db"
 
 func TestNew(t *testing.T) {
 	stg := jsonstorage.New(false)
@@ -39,6 +44,151 @@ func TestLoadAndGet(t *testing.T) {
 	assert.Equal(t, []byte("hoge!"), val)
 }
 
+func TestGetItem(t *testing.T) {
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	type Case struct {
+		stg      storage.Storage
+		file     string
+		expected *storage.Item
+		subtest  string
+	}
+	cases := []Case{
+		{
+			jsonstorage.New(false),
+			sampleDataFile,
+			&storage.Item{Value: []byte("hoge!"), CAS: 1},
+			"storage without metadata serves value without flags",
+		},
+		{
+			memcdstorage.New(boltstorage.New("goromdb")),
+			sampleMemcachedbFile,
+			&storage.Item{Value: []byte("hoge!"), Flags: 1, CAS: 1},
+			"storage with metadata serves value with flags",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			h := New(c.stg, logger)
+			err := h.Load(c.file)
+
+			assert.Nil(t, err)
+
+			item, err := h.GetItem([]byte("hoge"))
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, item)
+
+			item, err = h.GetItem([]byte("hogehoge"))
+
+			assert.Nil(t, item)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestGetItemCASFollowsGeneration(t *testing.T) {
+	stg := jsonstorage.New(false)
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := New(stg, logger)
+
+	assert.Equal(t, uint64(0), h.Generation())
+	assert.Nil(t, h.Load(sampleDataFile))
+
+	item1, _ := h.GetItem([]byte("hoge"))
+	item2, _ := h.GetItem([]byte("fuga"))
+
+	assert.Equal(t, uint64(1), item1.CAS)
+	assert.Equal(t, uint64(1), item2.CAS)
+
+	assert.NotNil(t, h.Load(sampleDataFile+".hoge"))
+	assert.Equal(t, uint64(1), h.Generation())
+
+	assert.Nil(t, h.Load(sampleDataFile))
+
+	item1, _ = h.GetItem([]byte("hoge"))
+
+	assert.Equal(t, uint64(2), item1.CAS)
+}
+
+func TestGetItemCASFollowsStorageGeneration(t *testing.T) {
+	stg := jsonstorage.New(false)
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := New(stg, logger)
+
+	assert.Nil(t, stg.Load(sampleDataFile))
+
+	item, err := h.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), item.CAS)
+	assert.Equal(t, stg.Generation(), h.Generation())
+}
+
+func TestGetItemCASFollowsSharedStorage(t *testing.T) {
+	shared := boltstorage.NewNS()
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h1 := New(shared.View("ns1"), logger)
+	h2 := New(shared.View("ns2"), logger)
+
+	assert.Nil(t, h1.Load(sampleNSBoltDBFile))
+
+	item, err := h2.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), item.CAS)
+
+	assert.Nil(t, h1.Load(sampleNSBoltDBFile))
+
+	item, err = h2.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), item.CAS)
+	assert.Equal(t, uint64(2), h2.Generation())
+}
+
+// reloadingStorage represents a storage loading new data while the first lookup reads
+type reloadingStorage struct {
+	generation uint64
+	reloads    int
+}
+
+func (s *reloadingStorage) Get(key []byte) ([]byte, error) {
+	if s.reloads > 0 {
+		s.reloads--
+		s.generation++
+		return []byte("old"), nil
+	}
+	return []byte("new"), nil
+}
+
+func (s *reloadingStorage) Load(file string) error {
+	return nil
+}
+
+func (s *reloadingStorage) Generation() uint64 {
+	return s.generation
+}
+
+func TestGetItemRereadsIfLoadedWhileReading(t *testing.T) {
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := New(&reloadingStorage{1, 1}, logger)
+	item, err := h.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &storage.Item{Value: []byte("new"), CAS: 2}, item)
+}
+
 func TestStart(t *testing.T) {
 	dir := testutil.CreateTmpDir()
 	defer os.RemoveAll(dir)
@@ -66,3 +216,100 @@ func TestStart(t *testing.T) {
 	close(filein)
 	<-done
 }
+
+func TestRange(t *testing.T) {
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := New(memcdstorage.New(boltstorage.New("goromdb")), logger)
+	err := h.Load(sampleMemcachedbFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		from         []byte
+		to           []byte
+		limit        int
+		expectedKeys []string
+		expectedNext []byte
+		subtest      string
+	}
+	cases := []Case{
+		{[]byte("b"), []byte("fuga"), 10, []string{"bar", "buz", "foo", "fuga"}, nil, "items in range within limit"},
+		{[]byte("b"), []byte("fuga"), 2, []string{"bar", "buz"}, []byte("foo"), "items in range up to limit with next key"},
+		{[]byte("foo"), nil, 2, []string{"foo", "fuga"}, []byte("hoge"), "items without upper bound"},
+		{[]byte("fuga"), nil, 2, []string{"fuga", "hoge"}, nil, "last page has no next key"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			var keys []string
+			next, err := h.Range(c.from, c.to, c.limit, func(key []byte, item *storage.Item) {
+				keys = append(keys, string(key))
+
+				assert.Equal(t, uint32(1), item.Flags)
+				assert.Equal(t, uint64(1), item.CAS)
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedKeys, keys)
+			assert.Equal(t, c.expectedNext, next)
+		})
+	}
+
+	h = New(jsonstorage.New(false), logger)
+	_, err = h.Range(nil, nil, 10, func(key []byte, item *storage.Item) {})
+
+	assert.NotNil(t, err)
+}
+
+func TestRangeCallsFnAfterReleasingStorage(t *testing.T) {
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := New(memcdstorage.New(boltstorage.New("goromdb")), logger)
+	h.Load(sampleMemcachedbFile)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.Range(nil, nil, 2, func(key []byte, item *storage.Item) {
+			// Loading waits for storage to be released
+			h.Load(sampleMemcachedbFile)
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("range holds storage while calling fn")
+	}
+}
+
+func TestStartAfterLoadAny(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := New(jsonstorage.New(false), logger)
+	l, _ := loader.New(dir, "test.data")
+
+	assert.False(t, h.LoadAny(l))
+
+	file := filepath.Join(dir, "dropin.db")
+	testutil.CopyFile(file, sampleDataFile)
+	l.DropIn(file)
+
+	assert.True(t, h.LoadAny(l))
+	assert.Equal(t, uint64(1), h.Generation())
+
+	filein := make(chan string)
+	done := h.Start(filein, l)
+	close(filein)
+	<-done
+
+	assert.Equal(t, uint64(1), h.Generation(), "data loaded before starting is not loaded again")
+}
@@ -8,7 +8,11 @@ import (
 )
 
 var (
-	_ handler.NSHandler = (*NSHandler)(nil)
+	_ handler.NSHandler      = (*NSHandler)(nil)
+	_ handler.NSItemHandler  = (*NSHandler)(nil)
+	_ handler.NSRangeHandler = (*NSHandler)(nil)
+	_ storage.NSIterable     = (*NSHandler)(nil)
+	_ storage.StatsReporter  = (*NSHandler)(nil)
 )
 
 // NSHandler represents a simple namespaced handler
@@ -19,10 +23,49 @@ type NSHandler struct {
 
 // NewNS create a handler with namespace storage
 func NewNS(stg storage.NSStorage, logger *log.Logger) *NSHandler {
-	return &NSHandler{Handler{StorageHandler{stg, logger}}, stg}
+	return &NSHandler{Handler{StorageHandler{stg, logger, new(uint64)}}, stg}
 }
 
 // GetNS finds value in namespace by given key, and returns the value
 func (h *NSHandler) GetNS(ns, key []byte) ([]byte, error) {
 	return h.nsstorage.GetNS(ns, key)
 }
+
+// GetItemNS finds value in namespace by given key, and returns the value with its metadata if storage serves any.
+// CAS of the item is the generation of loaded data the value is read from, as GetItem.
+func (h *NSHandler) GetItemNS(ns, key []byte) (*storage.Item, error) {
+	var item *storage.Item
+	cas, err := h.read(func() error {
+		var err error
+		item, err = storage.GetItemNS(h.nsstorage, ns, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	item.CAS = cas
+	return item, nil
+}
+
+// Namespaces returns namespaces in storage if it is iterable
+func (h *NSHandler) Namespaces() ([][]byte, error) {
+	return storage.Namespaces(h.nsstorage)
+}
+
+// KeysNS finds keys in namespace in storage if it is iterable, and returns up to limit keys having prefix starting at start
+func (h *NSHandler) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.KeysNS(h.nsstorage, ns, prefix, start, limit)
+}
+
+// CountNS counts keys having prefix in namespace in storage if it is iterable
+func (h *NSHandler) CountNS(ns, prefix []byte) (int, error) {
+	return storage.CountNS(h.nsstorage, ns, prefix)
+}
+
+// RangeNS finds items in a range of keys in namespace in storage if it supports range, and calls fn with up to limit items.
+// Returns a key to start next page at if more items exist.
+func (h *NSHandler) RangeNS(ns, from, to []byte, limit int, fn func([]byte, *storage.Item)) ([]byte, error) {
+	return h.page(limit, fn, func(f func([]byte, *storage.Item) bool) error {
+		return storage.RangeNS(h.nsstorage, ns, from, to, f)
+	})
+}
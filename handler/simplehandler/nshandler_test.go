@@ -9,11 +9,14 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yowcow/goromdb/loader"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/boltstorage"
 	"github.com/yowcow/goromdb/storage/jsonstorage"
 	"github.com/yowcow/goromdb/testutil"
 )
 
 var sampleNSDataFile = "../../_data/store/sample-ns-data.json"
+var sampleNSBoltDBFile = "../../_data/store/sample-ns-boltdb.db"
 
 func TestNewNS(t *testing.T) {
 	stg := jsonstorage.NewNS(false)
@@ -39,6 +42,28 @@ func TestLoadAndGetNS(t *testing.T) {
 	assert.Equal(t, []byte("hoge1"), val)
 }
 
+func TestGetItemNS(t *testing.T) {
+	logger := log.New(new(bytes.Buffer), "", 0)
+	h := NewNS(jsonstorage.NewNS(false), logger)
+	h.Load(sampleNSDataFile)
+
+	item, err := h.GetItemNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &storage.Item{Value: []byte("hoge1"), CAS: 1}, item)
+
+	h.Load(sampleNSDataFile)
+	item, err = h.GetItemNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), item.CAS)
+
+	item, err = h.GetItemNS([]byte("ns3"), []byte("hoge"))
+
+	assert.Nil(t, item)
+	assert.NotNil(t, err)
+}
+
 func TestStartNS(t *testing.T) {
 	dir := testutil.CreateTmpDir()
 	defer os.RemoveAll(dir)
@@ -66,3 +91,27 @@ func TestStartNS(t *testing.T) {
 	close(filein)
 	<-done
 }
+
+func TestRangeNS(t *testing.T) {
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	h := NewNS(boltstorage.NewNS(), logger)
+	err := h.Load(sampleNSBoltDBFile)
+
+	assert.Nil(t, err)
+
+	var actual []string
+	next, err := h.RangeNS([]byte("ns1"), nil, nil, 10, func(key []byte, item *storage.Item) {
+		actual = append(actual, string(key)+"="+string(item.Value))
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, []string{"hoge=hoge1"}, actual)
+
+	h = NewNS(jsonstorage.NewNS(false), logger)
+	_, err = h.RangeNS([]byte("ns1"), nil, nil, 10, func(key []byte, item *storage.Item) {})
+
+	assert.NotNil(t, err)
+}
@@ -2,6 +2,7 @@ package simplehandler
 
 import (
 	"log"
+	"sync/atomic"
 
 	"github.com/yowcow/goromdb/loader"
 	"github.com/yowcow/goromdb/storage"
@@ -9,11 +10,12 @@ import (
 
 // StorageHandler represents a wrapper to storage.Storage
 type StorageHandler struct {
-	storage storage.Storage
-	logger  *log.Logger
+	storage    storage.Storage
+	logger     *log.Logger
+	generation *uint64
 }
 
-// Start starts a handler goroutine
+// Start starts a handler goroutine, which loads a file found by loader first unless data has been loaded already
 func (h *StorageHandler) Start(filein <-chan string, l *loader.Loader) <-chan bool {
 	done := make(chan bool)
 	go h.start(filein, l, done)
@@ -26,12 +28,8 @@ func (h *StorageHandler) start(filein <-chan string, l *loader.Loader, done chan
 		close(done)
 	}()
 	h.logger.Println("simplehandler started")
-	if newfile, ok := l.FindAny(); ok {
-		if err := h.Load(newfile); err != nil {
-			h.logger.Printf("simplehandler failed loading data from '%s': %s", newfile, err.Error())
-		} else {
-			h.logger.Printf("simplehandler loaded data from '%s'", newfile)
-		}
+	if h.Generation() == 0 {
+		h.LoadAny(l)
 	}
 	for file := range filein {
 		h.logger.Printf("simplehandler got a new file to load at '%s'", file)
@@ -49,13 +47,99 @@ func (h *StorageHandler) start(filein <-chan string, l *loader.Loader, done chan
 		}
 
 		h.logger.Printf("simplehandler successfully loaded data from '%s'", newfile)
+		h.logMemoryEstimate()
 		if ok := l.CleanUp(); ok {
 			h.logger.Print("simplehandler successfully removed previously loaded file")
 		}
 	}
 }
 
-// Load loads data into storage
+// LoadAny loads a file found by loader if any, and returns whether or not data is loaded
+func (h *StorageHandler) LoadAny(l *loader.Loader) bool {
+	newfile, ok := l.FindAny()
+	if !ok {
+		return false
+	}
+	if err := h.Load(newfile); err != nil {
+		h.logger.Printf("simplehandler failed loading data from '%s': %s", newfile, err.Error())
+		return false
+	}
+	h.logger.Printf("simplehandler loaded data from '%s'", newfile)
+	h.logMemoryEstimate()
+	return true
+}
+
+// Load loads data into storage, and increments generation of loaded data
 func (h *StorageHandler) Load(file string) error {
-	return h.storage.Load(file)
+	if err := h.storage.Load(file); err != nil {
+		return err
+	}
+	atomic.AddUint64(h.generation, 1)
+	return nil
+}
+
+// Generation returns the generation of storage if it counts loads by itself, which changes together with data being served,
+// or otherwise the number of times data has been loaded by handler
+func (h *StorageHandler) Generation() uint64 {
+	if g, ok := h.storage.(storage.Generational); ok {
+		return g.Generation()
+	}
+	return atomic.LoadUint64(h.generation)
+}
+
+// read calls fn until no load happens while fn reads, and returns the generation of data fn has read
+func (h *StorageHandler) read(fn func() error) (uint64, error) {
+	for {
+		cas := h.Generation()
+		err := fn()
+		if h.Generation() == cas {
+			return cas, err
+		}
+	}
+}
+
+// page collects up to limit items found by iterate with CAS of loaded generation, and calls fn with them
+// after iterate returns, so that fn writing to a slow client never holds storage.
+// Returns a key of an item next to them if any.
+func (h *StorageHandler) page(
+	limit int,
+	fn func([]byte, *storage.Item),
+	iterate func(func([]byte, *storage.Item) bool) error,
+) ([]byte, error) {
+	var keys [][]byte
+	var items []*storage.Item
+	var next []byte
+	cas, err := h.read(func() error {
+		keys, items, next = nil, nil, nil
+		return iterate(func(key []byte, item *storage.Item) bool {
+			if len(keys) == limit {
+				next = key
+				return false
+			}
+			keys = append(keys, key)
+			items = append(items, item)
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		items[i].CAS = cas
+		fn(key, items[i])
+	}
+	return next, nil
+}
+
+// ReportStats calls fn with the generation and estimated bytes of loaded data, followed by statistics of storage
+func (h *StorageHandler) ReportStats(fn func(string, uint64)) {
+	fn("generation", h.Generation())
+	fn("memory_estimate", storage.MemoryEstimate(h.storage))
+	storage.ReportStats(h.storage, fn)
+}
+
+func (h *StorageHandler) logMemoryEstimate() {
+	if n := storage.MemoryEstimate(h.storage); n > 0 {
+		h.logger.Printf("simplehandler estimates loaded data takes %d bytes in memory", n)
+	}
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenerSpec represents an address to listen on, and the protocol to talk over it
+type listenerSpec struct {
+	network string
+	addr    string
+	proto   string
+	mode    os.FileMode
+	admin   bool
+}
+
+func (l listenerSpec) String() string {
+	if l.admin {
+		return fmt.Sprintf("%s://%s (%s, admin)", l.network, l.addr, l.proto)
+	}
+	return fmt.Sprintf("%s://%s (%s)", l.network, l.addr, l.proto)
+}
+
+// parseListeners parses comma-separated listener addresses.
+//
+// An address is either "host:port" for TCP, or in a form of "network://address?params".
+// Supported networks are tcp, tcp4, tcp6 and unix, and supported params are
+// "proto" for protocol, "mode" for unix socket file mode in octal, and "admin=true" to serve admin commands listing keys.
+func parseListeners(addrs, defaultProto, defaultMode string) ([]listenerSpec, error) {
+	var specs []listenerSpec
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		spec, err := parseListener(addr, defaultProto, defaultMode)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no address to listen on")
+	}
+	return specs, nil
+}
+
+func parseListener(addr, defaultProto, defaultMode string) (listenerSpec, error) {
+	if !strings.Contains(addr, "://") {
+		return listenerSpec{"tcp", addr, defaultProto, 0, false}, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return listenerSpec{}, err
+	}
+
+	spec := listenerSpec{u.Scheme, u.Host, defaultProto, 0, false}
+	if proto := u.Query().Get("proto"); proto != "" {
+		spec.proto = proto
+	}
+	if admin := u.Query().Get("admin"); admin != "" {
+		if spec.admin, err = strconv.ParseBool(admin); err != nil {
+			return listenerSpec{}, fmt.Errorf("invalid admin '%s' for '%s'", admin, addr)
+		}
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		return spec, nil
+	case "unix":
+		spec.addr = u.Host + u.Path
+		mode := defaultMode
+		if m := u.Query().Get("mode"); m != "" {
+			mode = m
+		}
+		if mode != "" {
+			perm, err := strconv.ParseUint(mode, 8, 32)
+			if err != nil || perm > 0777 {
+				return listenerSpec{}, fmt.Errorf("invalid socket mode '%s' for '%s'", mode, addr)
+			}
+			spec.mode = os.FileMode(perm)
+		}
+		return spec, nil
+	default:
+		return listenerSpec{}, fmt.Errorf("don't know how to listen on network '%s'", u.Scheme)
+	}
+}
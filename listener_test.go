@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListeners(t *testing.T) {
+	type Case struct {
+		subtest       string
+		input         string
+		expectedSpecs []listenerSpec
+		expectError   bool
+	}
+	cases := []Case{
+		{
+			"host:port is tcp",
+			":11211",
+			[]listenerSpec{
+				{"tcp", ":11211", "memcached", 0, false},
+			},
+			false,
+		},
+		{
+			"tcp and unix",
+			"tcp://127.0.0.1:11211, unix:///tmp/goromdb.sock",
+			[]listenerSpec{
+				{"tcp", "127.0.0.1:11211", "memcached", 0, false},
+				{"unix", "/tmp/goromdb.sock", "memcached", 0660, false},
+			},
+			false,
+		},
+		{
+			"params override defaults",
+			"unix:///tmp/goromdb.sock?mode=0600&proto=hoge",
+			[]listenerSpec{
+				{"unix", "/tmp/goromdb.sock", "hoge", 0600, false},
+			},
+			false,
+		},
+		{
+			"relative unix socket path",
+			"unix://goromdb.sock",
+			[]listenerSpec{
+				{"unix", "goromdb.sock", "memcached", 0660, false},
+			},
+			false,
+		},
+		{
+			"admin is enabled by param",
+			"unix:///tmp/goromdb.sock?admin=true, tcp://:11211?admin=false",
+			[]listenerSpec{
+				{"unix", "/tmp/goromdb.sock", "memcached", 0660, true},
+				{"tcp", ":11211", "memcached", 0, false},
+			},
+			false,
+		},
+		{
+			"invalid admin fails",
+			"unix:///tmp/goromdb.sock?admin=hoge",
+			nil,
+			true,
+		},
+		{
+			"invalid socket mode fails",
+			"unix:///tmp/goromdb.sock?mode=999",
+			nil,
+			true,
+		},
+		{
+			"unknown network fails",
+			"udp://:11211",
+			nil,
+			true,
+		},
+		{
+			"empty address fails",
+			" , ",
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			specs, err := parseListeners(c.input, "memcached", "0660")
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expectedSpecs, specs)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package loader
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/yowcow/goromdb/encfile"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	tarMagic  = []byte("ustar")
+)
+
+// tarMagicOffset defines the offset of magic in a tar header
+const tarMagicOffset = 257
+
+// FilePerm defines permission of a decompressed file
+const FilePerm = 0644
+
+// SecretFilePerm defines permission of a decrypted file
+const SecretFilePerm = 0600
+
+// SecretDirPerm defines permission of a directory extracted from a decrypted archive
+const SecretDirPerm = 0700
+
+// dropInFile moves file to target.
+// An encrypted file is decrypted with keys in keyFile, a file compressed in gzip or zstd is decompressed if decompress is true,
+// and a tar archive is extracted into a directory, at target instead.
+// Compression is detected by magic bytes or extension, and a tar archive by magic or extension after decompression.
+func dropInFile(file, target, keyFile string, decompress bool) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return os.Rename(file, target)
+	}
+
+	extracted, err := extractFile(file, target+".tmp", keyFile, decompress)
+	if err != nil {
+		os.RemoveAll(target + ".tmp")
+		return err
+	}
+	if !extracted {
+		return os.Rename(file, target)
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	if err := os.Rename(target+".tmp", target); err != nil {
+		return err
+	}
+	return os.Remove(file)
+}
+
+// extractFile decrypts, decompresses, and/or extracts, file into tmp, and returns true if file is any of encrypted, compressed or archived.
+// A compressed file is neither decompressed nor extracted unless decompress is true.
+func extractFile(file, tmp, keyFile string, decompress bool) (bool, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer fi.Close()
+
+	dr, encrypted, err := newDecryptReader(bufio.NewReader(fi), keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	var r io.ReadCloser = nopCloser{dr}
+	compressed := false
+	if decompress {
+		if r, compressed, err = newDecompressReader(file, bufio.NewReader(dr)); err != nil {
+			return false, err
+		}
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(r)
+	archived := (decompress || !isCompressed(file, br)) && isTar(file, br)
+	if !encrypted && !compressed && !archived {
+		return false, nil
+	}
+
+	if err := os.RemoveAll(tmp); err != nil {
+		return true, err
+	}
+	filePerm, dirPerm := os.FileMode(FilePerm), os.FileMode(DirPerm)
+	if encrypted {
+		// Never leave decrypted data readable by others
+		filePerm, dirPerm = SecretFilePerm, SecretDirPerm
+	}
+	if archived {
+		return true, extractTar(br, tmp, filePerm, dirPerm)
+	}
+	return true, writeFile(br, tmp, filePerm)
+}
+
+// newDecryptReader returns a reader decrypting r with keys in keyFile if it is encrypted, and whether or not it is encrypted
+func newDecryptReader(r *bufio.Reader, keyFile string) (io.Reader, bool, error) {
+	magic, _ := r.Peek(len(encfile.Magic))
+	if !encfile.IsEncrypted(magic) {
+		return r, false, nil
+	}
+	if keyFile == "" {
+		return nil, false, fmt.Errorf("file is encrypted but no key file is given")
+	}
+	keyring, err := encfile.ReadKeyFile(keyFile)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed reading key file: %s", err.Error())
+	}
+	er, err := encfile.NewReader(r, keyring)
+	if err != nil {
+		return nil, false, err
+	}
+	return er, true, nil
+}
+
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (r zstdReader) Close() error {
+	r.Decoder.Close()
+	return nil
+}
+
+// newDecompressReader returns a reader decompressing r if it is compressed, and whether or not it is compressed
+func newDecompressReader(file string, r *bufio.Reader) (io.ReadCloser, bool, error) {
+	magic, _ := r.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic) || hasSuffix(file, ".gz", ".tgz"):
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed reading gzip: %s", err.Error())
+		}
+		return gr, true, nil
+	case bytes.HasPrefix(magic, zstdMagic) || hasSuffix(file, ".zst", ".tzst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed reading zstd: %s", err.Error())
+		}
+		return zstdReader{zr}, true, nil
+	default:
+		return nopCloser{r}, false, nil
+	}
+}
+
+// isCompressed returns whether or not r looks compressed, without decompressing it
+func isCompressed(file string, r *bufio.Reader) bool {
+	magic, _ := r.Peek(len(zstdMagic))
+	return bytes.HasPrefix(magic, gzipMagic) || bytes.HasPrefix(magic, zstdMagic) || hasSuffix(file, ".gz", ".tgz", ".zst", ".tzst")
+}
+
+// IsArchiveName returns whether or not file is named as a tar archive
+func IsArchiveName(file string) bool {
+	return hasSuffix(file, ".tar", ".tgz", ".tzst", ".tar.gz", ".tar.zst")
+}
+
+func isTar(file string, r *bufio.Reader) bool {
+	if IsArchiveName(file) {
+		return true
+	}
+	header, _ := r.Peek(tarMagicOffset + len(tarMagic))
+	return len(header) == tarMagicOffset+len(tarMagic) && bytes.Equal(header[tarMagicOffset:], tarMagic)
+}
+
+func hasSuffix(file string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(file, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFile(r io.Reader, file string, perm os.FileMode) error {
+	fo, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fo, r); err != nil {
+		fo.Close()
+		return err
+	}
+	return fo.Close()
+}
+
+// extractTar extracts regular files and directories in a tar archive into dir
+func extractTar(r io.Reader, dir string, filePerm, dirPerm os.FileMode) error {
+	if err := os.Mkdir(dir, dirPerm); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed reading tar: %s", err.Error())
+		}
+
+		path := filepath.Join(dir, hdr.Name)
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			if path == dir {
+				continue
+			}
+			return fmt.Errorf("tar entry '%s' is out of directory", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, dirPerm)
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(path), dirPerm); err == nil {
+				err = writeFile(tr, path, filePerm)
+			}
+		default:
+			err = fmt.Errorf("tar entry '%s' is not a regular file nor a directory", hdr.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/encfile"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+type tarEntry struct {
+	name string
+	body string
+}
+
+func createTar(entries ...tarEntry) []byte {
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.name[len(e.name)-1] == '/' {
+			hdr = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			panic(err)
+		}
+		if _, err := io.WriteString(w, e.body); err != nil {
+			panic(err)
+		}
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func compressGzip(b []byte) []byte {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func compressZstd(b []byte) []byte {
+	w, _ := zstd.NewWriter(nil)
+	defer w.Close()
+	return w.EncodeAll(b, nil)
+}
+
+func TestDropInCompressedFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	content := []byte("hoge fuga")
+	tarball := createTar(tarEntry{"CURRENT", "hoge"}, tarEntry{"sub/", ""}, tarEntry{"sub/fuga", "fuga"})
+
+	type Case struct {
+		name          string
+		input         []byte
+		expectedFiles map[string]string
+		subtest       string
+	}
+	cases := []Case{
+		{"dropped-in", content, map[string]string{"": string(content)}, "plain file is moved"},
+		{"dropped-in", compressGzip(content), map[string]string{"": string(content)}, "gzip file is decompressed"},
+		{"dropped-in", compressZstd(content), map[string]string{"": string(content)}, "zstd file is decompressed"},
+		{"dropped-in", tarball, map[string]string{"CURRENT": "hoge", "sub/fuga": "fuga"}, "tar archive is extracted"},
+		{"dropped-in", compressGzip(tarball), map[string]string{"CURRENT": "hoge", "sub/fuga": "fuga"}, "gzipped tar archive is extracted"},
+		{"dropped-in", compressZstd(content), map[string]string{"": string(content)}, "file replaces extracted directory"},
+		{"dropped-in", compressZstd(tarball), map[string]string{"CURRENT": "hoge", "sub/fuga": "fuga"}, "zstd tar archive is extracted"},
+		{"dropped-in", tarball, map[string]string{"CURRENT": "hoge", "sub/fuga": "fuga"}, "tar archive replaces extracted directory"},
+	}
+
+	loader, _ := New(dir, "test.data")
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			input := filepath.Join(dir, c.name)
+			if err := ioutil.WriteFile(input, c.input, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := loader.DropIn(input)
+
+			assert.Nil(t, err)
+
+			for name, expected := range c.expectedFiles {
+				b, err := ioutil.ReadFile(filepath.Join(actual, name))
+
+				assert.Nil(t, err)
+				assert.Equal(t, expected, string(b))
+			}
+
+			_, err = os.Stat(input)
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Stat(actual + ".tmp")
+			assert.True(t, os.IsNotExist(err))
+
+			loader.CleanUp()
+		})
+	}
+}
+
+func TestDropInKeepCompressed(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	content := []byte("hoge fuga")
+	tarball := createTar(tarEntry{"CURRENT", "hoge"})
+
+	type Case struct {
+		name    string
+		input   []byte
+		subtest string
+	}
+	cases := []Case{
+		{"dropped-in", compressGzip(content), "gzip file is moved as it is"},
+		{"dropped-in.json.gz", compressGzip(content), "gzip file with extension is moved as it is"},
+		{"dropped-in", compressZstd(content), "zstd file is moved as it is"},
+		{"dropped-in.tar.gz", compressGzip(tarball), "gzipped tar archive is moved as it is"},
+	}
+
+	loader, _ := New(dir, "test.data")
+	loader.KeepCompressed()
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			input := filepath.Join(dir, c.name)
+			if err := ioutil.WriteFile(input, c.input, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := loader.DropIn(input)
+
+			assert.Nil(t, err)
+
+			b, err := ioutil.ReadFile(actual)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.input, b)
+
+			loader.CleanUp()
+		})
+	}
+}
+
+func TestDropInInvalidFileFails(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	type Case struct {
+		name    string
+		input   []byte
+		subtest string
+	}
+	cases := []Case{
+		{"dropped-in", compressGzip([]byte("hoge"))[:10], "truncated gzip file fails"},
+		{"dropped-in.gz", []byte("hoge"), "gzip extension without gzip content fails"},
+		{"dropped-in", createTar(tarEntry{"../hoge", "hoge"}), "tar entry out of directory fails"},
+		{"dropped-in.tar", []byte("hoge"), "tar extension without tar content fails"},
+	}
+
+	loader, _ := New(dir, "test.data")
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			input := filepath.Join(dir, c.name)
+			if err := ioutil.WriteFile(input, c.input, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := loader.DropIn(input)
+
+			assert.NotNil(t, err)
+
+			_, err = os.Stat(actual)
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Stat(actual + ".tmp")
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Stat(filepath.Join(dir, "hoge"))
+			assert.True(t, os.IsNotExist(err))
+
+			os.Remove(input)
+		})
+	}
+}
+
+func encrypt(b []byte, keyID, hexKey string) []byte {
+	key, _ := hex.DecodeString(hexKey)
+	buf := new(bytes.Buffer)
+	w, err := encfile.NewWriter(buf, keyID, key)
+	if err != nil {
+		panic(err)
+	}
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDropInEncryptedFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	oldKey := strings.Repeat("01", 32)
+	newKey := strings.Repeat("02", 16)
+	keyFile := filepath.Join(dir, "keys")
+	if err := ioutil.WriteFile(keyFile, []byte("old "+oldKey+"\nnew "+newKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("hoge fuga")
+	tarball := createTar(tarEntry{"CURRENT", "hoge"}, tarEntry{"sub/", ""}, tarEntry{"sub/fuga", "fuga"})
+
+	type Case struct {
+		input         []byte
+		expectedFiles map[string]string
+		subtest       string
+	}
+	cases := []Case{
+		{encrypt(content, "old", oldKey), map[string]string{"": string(content)}, "file encrypted with old key is decrypted"},
+		{encrypt(content, "new", newKey), map[string]string{"": string(content)}, "file encrypted with new key is decrypted"},
+		{encrypt(compressZstd(content), "new", newKey), map[string]string{"": string(content)}, "encrypted zstd file is decrypted and decompressed"},
+		{encrypt(compressGzip(tarball), "new", newKey), map[string]string{"CURRENT": "hoge", "sub/fuga": "fuga"}, "encrypted gzipped tar archive is extracted"},
+	}
+
+	loader, _ := New(dir, "test.data")
+	loader.UseKeyFile(keyFile)
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			input := filepath.Join(dir, "dropped-in")
+			if err := ioutil.WriteFile(input, c.input, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := loader.DropIn(input)
+
+			assert.Nil(t, err)
+
+			for name, expected := range c.expectedFiles {
+				path := filepath.Join(actual, name)
+				b, err := ioutil.ReadFile(path)
+
+				assert.Nil(t, err)
+				assert.Equal(t, expected, string(b))
+
+				fi, err := os.Stat(path)
+
+				assert.Nil(t, err)
+				assert.Equal(t, os.FileMode(SecretFilePerm), fi.Mode().Perm())
+			}
+
+			_, err = os.Stat(input)
+			assert.True(t, os.IsNotExist(err))
+
+			loader.CleanUp()
+		})
+	}
+}
+
+func TestDropInEncryptedFileFails(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	key := strings.Repeat("01", 32)
+	keyFile := filepath.Join(dir, "keys")
+	if err := ioutil.WriteFile(keyFile, []byte("old "+key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sealed := encrypt([]byte("hoge"), "old", key)
+
+	type Case struct {
+		input   []byte
+		keyFile string
+		subtest string
+	}
+	cases := []Case{
+		{sealed, "", "no key file fails"},
+		{sealed, filepath.Join(dir, "hoge"), "missing key file fails"},
+		{encrypt([]byte("hoge"), "new", key), keyFile, "unknown key id fails"},
+		{sealed[:len(sealed)-1], keyFile, "truncated file fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			loader, _ := New(dir, "test.data")
+			loader.UseKeyFile(c.keyFile)
+
+			input := filepath.Join(dir, "dropped-in")
+			if err := ioutil.WriteFile(input, c.input, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := loader.DropIn(input)
+
+			assert.NotNil(t, err)
+
+			_, err = os.Stat(actual)
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Stat(actual + ".tmp")
+			assert.True(t, os.IsNotExist(err))
+
+			os.Remove(input)
+		})
+	}
+}
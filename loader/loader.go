@@ -14,11 +14,14 @@ const DirPerm = 0755
 
 // Loader represents a loader
 type Loader struct {
-	basedir   string
-	filename  string
-	dirs      []string
-	curindex  int
-	previndex int
+	basedir        string
+	filename       string
+	dirs           []string
+	curindex       int
+	previndex      int
+	sidecars       []string
+	keyFile        string
+	keepCompressed bool
 }
 
 // New creates a new loader
@@ -27,7 +30,23 @@ func New(basedir, filename string) (*Loader, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Loader{basedir, filename, dirs, -1, -1}, nil
+	return &Loader{basedir, filename, dirs, -1, -1, nil, "", false}, nil
+}
+
+// AddSidecar makes loader move and clean a sidecar file, named with given suffix to a data file, together with the data file
+func (l *Loader) AddSidecar(suffix string) {
+	l.sidecars = append(l.sidecars, suffix)
+}
+
+// UseKeyFile makes loader decrypt an encrypted file with keys in keyFile.
+// Key file is read at every drop-in, so that a new key can be added without restart.
+func (l *Loader) UseKeyFile(keyFile string) {
+	l.keyFile = keyFile
+}
+
+// KeepCompressed makes loader drop in a compressed file as it is, for a storage decompressing a file on its own
+func (l *Loader) KeepCompressed() {
+	l.keepCompressed = true
 }
 
 func buildDirs(basedir string, count int) ([]string, error) {
@@ -81,12 +100,24 @@ func decrIndex(i *int) int {
 	return n
 }
 
-// DropIn drops given file into next subdirectory, and returns the filepath
+// DropIn drops given file, or directory, into next subdirectory, and returns the filepath.
+// An encrypted file, a compressed file, or a tar archive, is decrypted, decompressed, or extracted, into next subdirectory.
+// Sidecar files are moved first, and moved back if dropping in the data file fails, so that nothing is left half way.
 func (l *Loader) DropIn(file string) (string, error) {
 	nextindex := incrIndex(&l.curindex)
 	nextdir := l.dirs[nextindex]
 	nextfile := filepath.Join(nextdir, l.filename)
-	if err := os.Rename(file, nextfile); err != nil {
+	if err := removeStaleDir(file, nextfile); err != nil {
+		return nextfile, err
+	}
+	moved, err := l.dropInSidecars(file, nextfile)
+	if err == nil {
+		err = dropInFile(file, nextfile, l.keyFile, !l.keepCompressed)
+	}
+	if err != nil {
+		for _, suffix := range moved {
+			os.Rename(nextfile+suffix, file+suffix)
+		}
 		return nextfile, err
 	}
 	l.previndex = l.curindex
@@ -94,15 +125,49 @@ func (l *Loader) DropIn(file string) (string, error) {
 	return nextfile, nil
 }
 
-// CleanUp cleans previously loaded data file, and returns bool
+// dropInSidecars moves sidecar files of file next to target, and returns suffixes of sidecars moved
+func (l *Loader) dropInSidecars(file, target string) ([]string, error) {
+	var moved []string
+	for _, suffix := range l.sidecars {
+		if err := os.Rename(file+suffix, target+suffix); os.IsNotExist(err) {
+			// Never leave a sidecar of an older data file next to the new data file
+			os.Remove(target + suffix)
+		} else if err != nil {
+			return moved, err
+		} else {
+			moved = append(moved, suffix)
+		}
+	}
+	return moved, nil
+}
+
+// removeStaleDir removes target when either of file or target is a directory, since rename never replaces a directory
+func removeStaleDir(file, target string) error {
+	tfi, err := os.Stat(target)
+	if err != nil {
+		return nil
+	}
+	if fi, err := os.Stat(file); err != nil || (!fi.IsDir() && !tfi.IsDir()) {
+		return nil
+	}
+	return os.RemoveAll(target)
+}
+
+// CleanUp cleans previously loaded data file, or directory, and returns bool
 func (l *Loader) CleanUp() bool {
 	if l.previndex < 0 {
 		return false
 	}
 	prevdir := l.dirs[l.previndex]
 	prevfile := filepath.Join(prevdir, l.filename)
-	if err := os.Remove(prevfile); err != nil {
+	if _, err := os.Stat(prevfile); err != nil {
 		return false
 	}
+	if err := os.RemoveAll(prevfile); err != nil {
+		return false
+	}
+	for _, suffix := range l.sidecars {
+		os.Remove(prevfile + suffix)
+	}
 	return true
 }
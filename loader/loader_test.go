@@ -242,3 +242,159 @@ func TestCleanUp(t *testing.T) {
 		})
 	}
 }
+
+func TestDropInWithSidecar(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	type Case struct {
+		withSidecar     bool
+		expectedSidecar string
+		removedSidecar  string
+		subtest         string
+	}
+	cases := []Case{
+		{
+			false,
+			"",
+			filepath.Join(dir, "data00", "test.data.side"),
+			"1st drop-in without sidecar removes stale sidecar in data00",
+		},
+		{
+			true,
+			filepath.Join(dir, "data01", "test.data.side"),
+			"",
+			"2nd drop-in with sidecar moves sidecar into data01",
+		},
+		{
+			true,
+			filepath.Join(dir, "data00", "test.data.side"),
+			filepath.Join(dir, "data01", "test.data.side"),
+			"3rd drop-in with sidecar moves sidecar into data00, and cleans sidecar in data01",
+		},
+	}
+
+	loader, _ := New(dir, "test.data")
+	loader.AddSidecar(".side")
+
+	// stale sidecar from older data file
+	testutil.CopyFile(filepath.Join(dir, "data00", "test.data.side"), "loader_test.go")
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			input := filepath.Join(dir, "dropped-in")
+			testutil.CopyFile(input, "loader_test.go")
+			if c.withSidecar {
+				testutil.CopyFile(input+".side", "loader_test.go")
+			}
+
+			_, err := loader.DropIn(input)
+			assert.Nil(t, err)
+
+			loader.CleanUp()
+
+			if c.expectedSidecar != "" {
+				_, err = os.Stat(c.expectedSidecar)
+				assert.Nil(t, err)
+			}
+			if c.removedSidecar != "" {
+				_, err = os.Stat(c.removedSidecar)
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
+
+func TestDropInFailureMovesSidecarBack(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	loader, _ := New(dir, "test.data")
+	loader.AddSidecar(".side")
+
+	input := filepath.Join(dir, "dropped-in.gz")
+	testutil.CopyFile(input, "loader_test.go")
+	testutil.CopyFile(input+".side", "loader_test.go")
+
+	_, err := loader.DropIn(input)
+
+	assert.NotNil(t, err)
+
+	_, err = os.Stat(input + ".side")
+
+	assert.Nil(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "data00", "test.data.side"))
+
+	assert.True(t, os.IsNotExist(err))
+
+	os.Rename(input, filepath.Join(dir, "dropped-in"))
+	os.Rename(input+".side", filepath.Join(dir, "dropped-in.side"))
+	actual, err := loader.DropIn(filepath.Join(dir, "dropped-in"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "data00", "test.data"), actual)
+
+	_, err = os.Stat(actual + ".side")
+
+	assert.Nil(t, err)
+}
+
+func TestDropInAndCleanUpDir(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	type Case struct {
+		expectedFilepath string
+		removedFilepath  string
+		subtest          string
+	}
+	cases := []Case{
+		{
+			filepath.Join(dir, "data00", "test.data"),
+			"",
+			"1st drop-in replaces stale directory in data00",
+		},
+		{
+			filepath.Join(dir, "data01", "test.data"),
+			filepath.Join(dir, "data00", "test.data"),
+			"2nd drop-in stores into data01, and cleans directory in data00",
+		},
+		{
+			filepath.Join(dir, "data00", "test.data"),
+			filepath.Join(dir, "data01", "test.data"),
+			"3rd drop-in stores into data00, and cleans directory in data01",
+		},
+	}
+
+	loader, _ := New(dir, "test.data")
+
+	// stale directory from older data
+	os.Mkdir(filepath.Join(dir, "data00", "test.data"), DirPerm)
+	testutil.CopyFile(filepath.Join(dir, "data00", "test.data", "stale"), "loader_test.go")
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			input := filepath.Join(dir, "dropped-in")
+			os.Mkdir(input, DirPerm)
+			testutil.CopyFile(filepath.Join(input, "CURRENT"), "loader_test.go")
+
+			actual, err := loader.DropIn(input)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedFilepath, actual)
+
+			_, err = os.Stat(filepath.Join(actual, "CURRENT"))
+			assert.Nil(t, err)
+			_, err = os.Stat(filepath.Join(actual, "stale"))
+			assert.True(t, os.IsNotExist(err))
+
+			loader.CleanUp()
+
+			if c.removedFilepath != "" {
+				_, err = os.Stat(c.removedFilepath)
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+}
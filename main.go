@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/handler/fallbackhandler"
 	"github.com/yowcow/goromdb/handler/simplehandler"
 	"github.com/yowcow/goromdb/loader"
 	"github.com/yowcow/goromdb/protocol"
@@ -17,8 +23,14 @@ import (
 	"github.com/yowcow/goromdb/storage"
 	"github.com/yowcow/goromdb/storage/bdbstorage"
 	"github.com/yowcow/goromdb/storage/boltstorage"
+	"github.com/yowcow/goromdb/storage/cdbstorage"
+	"github.com/yowcow/goromdb/storage/compressstorage"
 	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/storage/leveldbstorage"
 	"github.com/yowcow/goromdb/storage/memcdstorage"
+	"github.com/yowcow/goromdb/storage/ndjsonstorage"
+	"github.com/yowcow/goromdb/storage/nskey"
+	"github.com/yowcow/goromdb/storage/sqlitestorage"
 	"github.com/yowcow/goromdb/watcher"
 )
 
@@ -28,23 +40,51 @@ var Version string
 func main() {
 	var addr string
 	var protoBackend string
-	var handlerBackend string
-	var storageBackend string
-	var file string
-	var gzipped bool
-	var bucket string
-	var basedir string
+	var configFile string
+	var base dbConfig
+	var socketMode string
+	var tlsCert string
+	var tlsKey string
+	var tlsClientCA string
+	var tlsReloadInterval int
+	var shutdownTimeout int
+	var fallback string
 	var help bool
 	var version bool
 
-	flag.StringVar(&addr, "addr", ":11211", "address to bind to")
-	flag.StringVar(&protoBackend, "proto", "memcached", "protocol: memcached")
-	flag.StringVar(&handlerBackend, "handler", "simple", "handler: simple")
-	flag.StringVar(&storageBackend, "storage", "json", "storage: json, bdb, boltdb, memcachedb-bdb")
-	flag.StringVar(&file, "file", "/tmp/goromdb", "data file to be loaded into store")
-	flag.BoolVar(&gzipped, "gzipped", false, "whether or not loading file is gzipped")
-	flag.StringVar(&bucket, "bucket", "default", "bucket name (for boltdb)")
-	flag.StringVar(&basedir, "basedir", "", "base directory to store loaded data file")
+	flag.StringVar(&addr, "addr", ":11211", "comma-separated addresses to bind to (e.g. ':11211,unix:///tmp/goromdb.sock?mode=0660')")
+	flag.StringVar(&protoBackend, "proto", "memcached", "default protocol: memcached")
+	flag.StringVar(&configFile, "config", "", "config file of databases to serve, reloaded on SIGHUP (flags below are defaults of databases in it)")
+	flag.StringVar(&base.Handler, "handler", "simple", "handler: simple, fallback (finds a key in namespaces of a namespaced storage in order)")
+	flag.StringVar(&fallback, "fallback", "", "comma-separated namespaces to find a key in, in order (for fallback handler)")
+	flag.BoolVar(&base.FallbackDebug, "fallback-debug", false, "whether or not to log which namespace served each value (for fallback handler)")
+	flag.StringVar(&base.NSEncoding, "ns-encoding", "length", "namespace key encoding of namespaced storage: concat, length, separator=<separator> (for fallback handler over bdb, cdb, leveldb, memcachedb codec)")
+	flag.StringVar(&base.Storage, "storage", "json", "storage: json, ndjson, bdb, boltdb, cdb, leveldb, sqlite, memcachedb-bdb (alias to bdb with memcachedb codec)")
+	flag.StringVar(&base.Codec, "codec", "none", "value codec: none, memcachedb")
+	flag.StringVar(&base.Compression, "compression", "none", "value compression: none, gzip, zstd, snappy, lz4, header (first byte of value tells compression)")
+	flag.StringVar(&base.File, "file", "/tmp/goromdb", "data file to be loaded into store")
+	flag.IntVar(&base.WatchInterval, "watch-interval", 5000, "interval in milliseconds to check data file for a new one")
+	flag.StringVar(&base.Checksum, "checksum", "none", "checksum to verify a data file with before loading: none, md5 (a hex md5 sum in a file like data.db.md5)")
+	flag.BoolVar(&base.Gzipped, "gzipped", false, "whether or not loading file is gzipped, and decompressed by storage instead of loader (for json, ndjson)")
+	flag.StringVar(&base.JSONValues, "json-values", "string", "JSON value types to accept: string, any (for json, ndjson, values other than strings are served in JSON)")
+	flag.StringVar(&base.Bucket, "bucket", "default", "bucket name (for boltdb)")
+	flag.Float64Var(&base.BloomFPRate, "bloom-fp-rate", 0, "false positive rate of bloom filter to find non-existing keys with (for boltdb, 0 disables)")
+	flag.IntVar(&base.Shards, "shards", 0, "number of shards to spread keys over, loaded from files like data.shard-00.db in a directory or tar archive (0 disables)")
+	flag.StringVar(&base.ShardHash, "shard-hash", "jump", "hash to choose a shard of a key with: crc32, xxhash, jump")
+	flag.StringVar(&base.SQLiteTable, "sqlite-table", "goromdb", "table name (for sqlite)")
+	flag.StringVar(&base.SQLiteKeyColumn, "sqlite-key-column", "key", "key column name (for sqlite)")
+	flag.StringVar(&base.SQLiteValueColumn, "sqlite-value-column", "value", "value column name (for sqlite)")
+	flag.StringVar(&base.SQLiteNSColumn, "sqlite-ns-column", "ns", "namespace column name (for sqlite with fallback handler)")
+	flag.StringVar(&base.Basedir, "basedir", "", "base directory to store loaded data file")
+	flag.StringVar(&base.KeyFile, "key-file", "", "key file to decrypt encrypted data files with (a key id and a hex encoded AES key in each line)")
+	flag.IntVar(&base.CacheBytes, "cache-bytes", 0, "max bytes of values to cache in memory (0 disables cache)")
+	flag.BoolVar(&base.CacheMisses, "cache-misses", false, "whether or not to cache keys not found")
+	flag.StringVar(&socketMode, "socket-mode", "", "default file mode in octal for unix domain sockets")
+	flag.StringVar(&tlsCert, "tls-cert", "", "TLS certificate file (enables TLS)")
+	flag.StringVar(&tlsKey, "tls-key", "", "TLS private key file")
+	flag.StringVar(&tlsClientCA, "tls-client-ca", "", "CA certificate file to verify client certificates against (enables mutual TLS)")
+	flag.IntVar(&tlsReloadInterval, "tls-reload-interval", 5000, "interval in milliseconds to check TLS certificate files for changes")
+	flag.IntVar(&shutdownTimeout, "shutdown-timeout", 10000, "time in milliseconds to wait for busy connections to finish at shutdown")
 	flag.BoolVar(&help, "help", false, "print help")
 	flag.BoolVar(&version, "version", false, "print version")
 	flag.Parse()
@@ -59,57 +99,165 @@ func main() {
 		os.Exit(0)
 	}
 
+	if fallback != "" {
+		base.Fallback = strings.Split(fallback, ",")
+	}
+
 	logger := log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
 
-	proto, err := createProtocol(protoBackend)
+	specs, err := parseListeners(addr, protoBackend, socketMode)
 	if err != nil {
 		panic(err)
 	}
+	protos := make([]protocol.Protocol, len(specs))
+	for i, spec := range specs {
+		if protos[i], err = createProtocol(spec.proto); err != nil {
+			panic(err)
+		}
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	wcr := watcher.NewSimpleWatcher(file, 5000, logger)
-	filein := wcr.Start(ctx)
-
-	stg, err := createStorage(storageBackend, gzipped, bucket)
+	readCurrentConfig := func() (*config, error) {
+		if configFile == "" {
+			return singleConfig(base), nil
+		}
+		return readConfig(configFile, base)
+	}
+	cfg, err := readCurrentConfig()
 	if err != nil {
 		panic(err)
 	}
 
-	l, err := loader.New(basedir, "data.db")
-	if err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	dbs := newDatabases(logger)
+	if err := dbs.reconfigure(ctx, cfg); err != nil {
 		panic(err)
 	}
 
-	h, err := createHandler(handlerBackend, stg, logger)
+	tlsConfig, err := createTLSConfig(ctx, tlsCert, tlsKey, tlsClientCA, tlsReloadInterval, logger)
 	if err != nil {
 		panic(err)
 	}
-	done := h.Start(filein, l)
 
-	logger.Printf(
-		"booting goromdb (PID: %d, address: %s, protocol: %s, handler: %s, storage: %s, file: %s)",
-		os.Getpid(), addr, protoBackend, handlerBackend, storageBackend, file,
-	)
+	if configFile != "" {
+		logger.Printf("booting goromdb (PID: %d, address: %s, config: %s)", os.Getpid(), addr, configFile)
+	} else {
+		logger.Printf(
+			"booting goromdb (PID: %d, address: %s, handler: %s, storage: %s, codec: %s, file: %s)",
+			os.Getpid(), addr, base.Handler, base.Storage, base.Codec, base.File,
+		)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+	errs := make(chan error, len(specs))
+	servers := make([]*server.Server, len(specs))
+	for i, spec := range specs {
+		servers[i] = createServer(spec, tlsConfig, logger)
+		logger.Printf("listening on %s", spec)
+		go func(svr *server.Server, proto protocol.Protocol, admin bool) {
+			errs <- svr.Start(createCallback(proto, dbs.route, admin))
+		}(servers[i], protos[i], spec.admin)
+	}
+
+	for running := true; running; {
+		select {
+		case err := <-errs:
+			if err != nil {
+				logger.Printf("failed booting goromdb: %s", err.Error())
+				os.Exit(1)
+			}
+		case sig := <-sigs:
+			if sig != syscall.SIGHUP {
+				logger.Printf("shutting down goromdb on %s", sig)
+				running = false
+			} else if configFile == "" {
+				logger.Print("goromdb has no config file to reload")
+			} else if cfg, err := readCurrentConfig(); err != nil {
+				logger.Printf("failed reloading config: %s", err.Error())
+			} else if err := dbs.reconfigure(ctx, cfg); err != nil {
+				logger.Printf("failed reloading config: %s", err.Error())
+			} else {
+				logger.Printf("reloaded config from %s", configFile)
+			}
+		}
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Duration(shutdownTimeout)*time.Millisecond)
+	defer cancelShutdown()
+	for _, svr := range servers {
+		if err := svr.Shutdown(shutdownCtx); err != nil {
+			logger.Printf("closed busy connections at shutdown: %s", err.Error())
+		}
+	}
+	cancel()
+	dbs.stopAll()
+}
 
-	svr := server.New("tcp", addr, logger)
-	err = svr.Start(server.OnReadCallbackFunc(func(conn net.Conn, line []byte, logger *log.Logger) {
-		if keys, err := proto.Parse(line); err != nil {
+// createCallback creates a callback finding keys in handlers given by route.
+// Commands with arguments are run against the handler of a nil key, which is the default database,
+// and admin commands are refused unless admin is true.
+func createCallback(proto protocol.Protocol, route func([]byte) (handler.Handler, []byte), admin bool) server.OnReadCallbackFunc {
+	return func(conn net.Conn, line []byte, logger *log.Logger) {
+		if cmd, err := proto.Parse(line); err != nil {
 			logger.Printf("server failed parsing a line: %s", err)
 		} else {
-			for _, k := range keys {
-				if v, _ := h.Get(k); v != nil {
-					proto.Reply(conn, k, v)
+			// Commands with arguments reply their own end of message
+			switch cmd.Name {
+			case protocol.AdminCommand, protocol.RangeCommand, protocol.RangeNSCommand:
+				h, _ := route(nil)
+				if cmd.Name == protocol.AdminCommand && !admin {
+					writeError(conn, &argsError{fmt.Errorf("admin commands are not enabled on this listener")})
+				} else if h == nil {
+					writeError(conn, storage.InternalError("no default database"))
+				} else if cmd.Name == protocol.AdminCommand {
+					runAdmin(conn, cmd.Args, h)
+				} else {
+					runRange(proto, conn, cmd, h)
+				}
+				return
+			}
+			for _, k := range cmd.Keys {
+				h, key := route(k)
+				if h == nil {
+					continue
+				}
+				if item, _ := h.GetItem(key); item != nil {
+					proto.Reply(conn, cmd, k, item)
 				}
 			}
 		}
 		proto.Finish(conn)
-	}))
+	}
+}
+
+func createTLSConfig(
+	ctx context.Context,
+	tlsCert, tlsKey, tlsClientCA string,
+	tlsReloadInterval int,
+	logger *log.Logger,
+) (*tls.Config, error) {
+	if tlsCert == "" {
+		return nil, nil
+	}
+	r, err := server.NewCertReloader(tlsCert, tlsKey, tlsReloadInterval, logger)
 	if err != nil {
-		logger.Printf("failed booting goromdb: %s", err.Error())
-		os.Exit(1)
+		return nil, err
+	}
+	r.Start(ctx)
+	return server.NewTLSConfig(r, tlsClientCA)
+}
+
+func createServer(spec listenerSpec, tlsConfig *tls.Config, logger *log.Logger) *server.Server {
+	switch {
+	case spec.network == "unix":
+		// TLS is not applied to unix domain sockets reachable only from the same host
+		return server.NewUnix(spec.addr, spec.mode, logger)
+	case tlsConfig != nil:
+		return server.NewTLS(spec.network, spec.addr, tlsConfig, logger)
+	default:
+		return server.New(spec.network, spec.addr, logger)
 	}
-	cancel()
-	<-done
 }
 
 func createHandler(
@@ -125,22 +273,196 @@ func createHandler(
 	}
 }
 
-func createStorage(storageBackend string, gzipped bool, bucket string) (storage.Storage, error) {
+// createFallbackHandler creates a handler finding a key in namespaces of stg in order
+func createFallbackHandler(namespaces []string, debug bool, stg storage.NSStorage, logger *log.Logger) (handler.Handler, error) {
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("fallback handler needs namespaces to find keys in")
+	}
+	h := fallbackhandler.New(simplehandler.NewNS(stg, logger), namespaces, logger)
+	if debug {
+		h.UseDebugLog()
+	}
+	return h, nil
+}
+
+// createWatcher creates a watcher of a data file, or of a directory when a database is a directory.
+// A checksum is verified against a data file as it is dropped in, which is a compressed file if compressed.
+func createWatcher(cfg dbConfig, logger *log.Logger) (watcher.Watcher, error) {
+	// LevelDB database and shard set are a directory, unless archived
+	isDir := (cfg.Storage == "leveldb" || cfg.Shards > 0) && !loader.IsArchiveName(cfg.File)
+	switch cfg.Checksum {
+	case "none":
+		if isDir {
+			return watcher.NewDirWatcher(cfg.File, cfg.WatchInterval, logger), nil
+		}
+		return watcher.NewSimpleWatcher(cfg.File, cfg.WatchInterval, logger), nil
+	case "md5":
+		if isDir {
+			return nil, fmt.Errorf("checksum is not verified against a directory, archive it instead")
+		}
+		return watcher.NewMD5Watcher(cfg.File, cfg.WatchInterval, logger), nil
+	default:
+		return nil, fmt.Errorf("don't know how to handle checksum '%s'", cfg.Checksum)
+	}
+}
+
+func createStorage(
+	storageBackend string,
+	gzipped bool,
+	jsonValues string,
+	bucket string,
+	bloomFPRate float64,
+	sqliteTable, sqliteKeyCol, sqliteValueCol string,
+) (storage.Storage, error) {
 	switch storageBackend {
 	case "json":
-		return jsonstorage.New(gzipped), nil
+		mode, err := parseJSONValueMode(jsonValues)
+		if err != nil {
+			return nil, err
+		}
+		s := jsonstorage.New(gzipped)
+		s.SetValueMode(mode)
+		return s, nil
+	case "ndjson":
+		mode, err := parseJSONValueMode(jsonValues)
+		if err != nil {
+			return nil, err
+		}
+		s := ndjsonstorage.New(gzipped)
+		s.SetValueMode(mode)
+		return s, nil
 	case "bdb":
 		return bdbstorage.New(), nil
 	case "boltdb":
-		return boltstorage.New(bucket), nil
-	case "memcachedb-bdb":
-		p := bdbstorage.New()
-		return memcdstorage.New(p), nil
+		s := boltstorage.New(bucket)
+		s.UseBloomFilter(bloomFPRate)
+		return s, nil
+	case "cdb":
+		return cdbstorage.New(), nil
+	case "leveldb":
+		return leveldbstorage.New(), nil
+	case "sqlite":
+		return sqlitestorage.New(sqliteTable, sqliteKeyCol, sqliteValueCol), nil
 	default:
 		return nil, fmt.Errorf("don't know how to handle storage '%s'", storageBackend)
 	}
 }
 
+// createNSStorage creates a namespaced storage, which encodes a namespace and a key into a single key with enc
+// if the database has no namespaces of its own
+func createNSStorage(
+	storageBackend string,
+	gzipped bool,
+	jsonValues string,
+	bloomFPRate float64,
+	sqliteTable, sqliteNSCol, sqliteKeyCol, sqliteValueCol string,
+	enc nskey.Encoding,
+) (storage.NSStorage, error) {
+	switch storageBackend {
+	case "json":
+		mode, err := parseJSONValueMode(jsonValues)
+		if err != nil {
+			return nil, err
+		}
+		s := jsonstorage.NewNS(gzipped)
+		s.SetValueMode(mode)
+		return s, nil
+	case "ndjson":
+		mode, err := parseJSONValueMode(jsonValues)
+		if err != nil {
+			return nil, err
+		}
+		s := ndjsonstorage.NewNS(gzipped)
+		s.SetValueMode(mode)
+		return s, nil
+	case "bdb":
+		return bdbstorage.NewNS(enc), nil
+	case "boltdb":
+		s := boltstorage.NewNS()
+		s.UseBloomFilter(bloomFPRate)
+		return s, nil
+	case "cdb":
+		return cdbstorage.NewNS(enc), nil
+	case "leveldb":
+		sep, ok := enc.(nskey.Separator)
+		if !ok {
+			return nil, fmt.Errorf("namespaced leveldb storage takes only separator=<separator> namespace key encoding")
+		}
+		return leveldbstorage.NewNS(string(sep)), nil
+	case "sqlite":
+		return sqlitestorage.NewNS(sqliteTable, sqliteNSCol, sqliteKeyCol, sqliteValueCol), nil
+	default:
+		return nil, fmt.Errorf("don't know how to handle namespaced storage '%s'", storageBackend)
+	}
+}
+
+// storageAliases maps a storage name kept for compatibility to its storage and codec
+var storageAliases = map[string][2]string{
+	"memcachedb-bdb": {"bdb", "memcachedb"},
+}
+
+func resolveStorageAlias(storageBackend, codec string) (string, string) {
+	if alias, ok := storageAliases[storageBackend]; ok {
+		return alias[0], alias[1]
+	}
+	return storageBackend, codec
+}
+
+func applyCompression(compression string, stg storage.Storage) (storage.Storage, error) {
+	c, err := compressstorage.ParseCodec(compression)
+	if err != nil {
+		return nil, err
+	}
+	if c == compressstorage.None {
+		return stg, nil
+	}
+	return compressstorage.New(stg, c), nil
+}
+
+func applyNSCompression(compression string, stg storage.NSStorage) (storage.NSStorage, error) {
+	c, err := compressstorage.ParseCodec(compression)
+	if err != nil {
+		return nil, err
+	}
+	if c == compressstorage.None {
+		return stg, nil
+	}
+	return compressstorage.NewNS(stg, c), nil
+}
+
+func applyCodec(codec string, stg storage.Storage) (storage.Storage, error) {
+	switch codec {
+	case "none":
+		return stg, nil
+	case "memcachedb":
+		return memcdstorage.New(stg), nil
+	default:
+		return nil, fmt.Errorf("don't know how to handle codec '%s'", codec)
+	}
+}
+
+func applyNSCodec(codec string, stg storage.NSStorage, enc nskey.Encoding) (storage.NSStorage, error) {
+	switch codec {
+	case "none":
+		return stg, nil
+	case "memcachedb":
+		return memcdstorage.NewNS(stg, enc), nil
+	default:
+		return nil, fmt.Errorf("don't know how to handle codec '%s'", codec)
+	}
+}
+
+func parseJSONValueMode(jsonValues string) (jsonstorage.ValueMode, error) {
+	switch jsonValues {
+	case "string":
+		return jsonstorage.StringValues, nil
+	case "any":
+		return jsonstorage.RawValues, nil
+	default:
+		return 0, fmt.Errorf("don't know how to handle json values '%s'", jsonValues)
+	}
+}
+
 func createProtocol(protoBackend string) (protocol.Protocol, error) {
 	switch protoBackend {
 	case "memcached":
@@ -6,11 +6,19 @@ import (
 	"io"
 
 	"github.com/yowcow/goromdb/protocol"
+	"github.com/yowcow/goromdb/storage"
 )
 
 // Prefixes defines memcached protocol command prefixes to parse
 var Prefixes = [][]byte{[]byte("gets "), []byte("get ")}
 
+// ArgsPrefixes defines prefixes of commands with arguments to parse
+var ArgsPrefixes = [][]byte{
+	[]byte(protocol.AdminCommand + " "),
+	[]byte(protocol.RangeCommand + " "),
+	[]byte(protocol.RangeNSCommand + " "),
+}
+
 // Space defines a space in []byte
 var Space = []byte(" ")
 
@@ -23,20 +31,30 @@ func New() protocol.Protocol {
 	return &Protocol{}
 }
 
-// Parse parses given line into keys to search
-func (p *Protocol) Parse(line []byte) ([][]byte, error) {
+// Parse parses given line into a command with keys to search, or into a command with arguments
+func (p *Protocol) Parse(line []byte) (*protocol.Command, error) {
+	for _, prefix := range ArgsPrefixes {
+		if bytes.HasPrefix(line, prefix) {
+			words := bytes.Split(line, Space)
+			return &protocol.Command{Name: string(words[0]), Args: words[1:]}, nil
+		}
+	}
 	for _, prefix := range Prefixes {
 		if bytes.HasPrefix(line, prefix) {
 			words := bytes.Split(line, Space)
-			return words[1:], nil
+			return &protocol.Command{Name: string(words[0]), Keys: words[1:]}, nil
 		}
 	}
-	return [][]byte{}, protocol.InvalidCommandError(line)
+	return nil, protocol.InvalidCommandError(line)
 }
 
-// Reply writes reply message to writer
-func (p *Protocol) Reply(w io.Writer, k, v []byte) {
-	fmt.Fprintf(w, "VALUE %s 0 %d\r\n%s\r\n", string(k), len(v), string(v))
+// Reply writes reply message with value and flags of given item to writer, and CAS as well for gets command
+func (p *Protocol) Reply(w io.Writer, cmd *protocol.Command, k []byte, item *storage.Item) {
+	if cmd.Name == "gets" {
+		fmt.Fprintf(w, "VALUE %s %d %d %d\r\n%s\r\n", string(k), item.Flags, len(item.Value), item.CAS, string(item.Value))
+		return
+	}
+	fmt.Fprintf(w, "VALUE %s %d %d\r\n%s\r\n", string(k), item.Flags, len(item.Value), string(item.Value))
 }
 
 // Finish writes an end of message to writer
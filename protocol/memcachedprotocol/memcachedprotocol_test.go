@@ -6,45 +6,106 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/protocol"
+	"github.com/yowcow/goromdb/storage"
 )
 
 func TestParse_on_get_command(t *testing.T) {
 	p := New()
-	words, err := p.Parse([]byte("get hoge"))
+	cmd, err := p.Parse([]byte("get hoge"))
 
-	assert.Equal(t, 1, len(words))
-	assert.Equal(t, []byte("hoge"), words[0])
 	assert.Nil(t, err)
+	assert.Equal(t, "get", cmd.Name)
+	assert.Equal(t, 1, len(cmd.Keys))
+	assert.Equal(t, []byte("hoge"), cmd.Keys[0])
 }
 
 func TestParse_on_gets_command(t *testing.T) {
 	p := New()
-	words, err := p.Parse([]byte("gets hoge fuga"))
+	cmd, err := p.Parse([]byte("gets hoge fuga"))
 
 	assert.Nil(t, err)
-	assert.Equal(t, 2, len(words))
-	assert.Equal(t, []byte("hoge"), words[0])
-	assert.Equal(t, []byte("fuga"), words[1])
+	assert.Equal(t, "gets", cmd.Name)
+	assert.Equal(t, 2, len(cmd.Keys))
+	assert.Equal(t, []byte("hoge"), cmd.Keys[0])
+	assert.Equal(t, []byte("fuga"), cmd.Keys[1])
+}
+
+func TestParse_on_admin_command(t *testing.T) {
+	p := New()
+	cmd, err := p.Parse([]byte("admin keys 10 ho"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, protocol.AdminCommand, cmd.Name)
+	assert.Equal(t, 0, len(cmd.Keys))
+	assert.Equal(t, [][]byte{[]byte("keys"), []byte("10"), []byte("ho")}, cmd.Args)
+}
+
+func TestParse_on_range_commands(t *testing.T) {
+	p := New()
+	cmd, err := p.Parse([]byte("range 10 a b"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, protocol.RangeCommand, cmd.Name)
+	assert.Equal(t, [][]byte{[]byte("10"), []byte("a"), []byte("b")}, cmd.Args)
+
+	cmd, err = p.Parse([]byte("range-ns ns1 10 a"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, protocol.RangeNSCommand, cmd.Name)
+	assert.Equal(t, [][]byte{[]byte("ns1"), []byte("10"), []byte("a")}, cmd.Args)
 }
 
 func TestParse_on_invalid_command(t *testing.T) {
 	p := New()
-	words, err := p.Parse([]byte("set hoge fuga foo bar"))
+	cmd, err := p.Parse([]byte("set hoge fuga foo bar"))
 
 	assert.Equal(t, "invalid command: set hoge fuga foo bar", err.Error())
-	assert.Equal(t, 0, len(words))
+	assert.Nil(t, cmd)
 }
 
 func TestReply(t *testing.T) {
-	buf := new(bytes.Buffer)
-	w := bufio.NewWriter(buf)
+	type Case struct {
+		cmd      string
+		input    *storage.Item
+		expected string
+		subtest  string
+	}
+	cases := []Case{
+		{
+			"get",
+			&storage.Item{Value: []byte("hogefuga")},
+			"VALUE hoge 0 8\r\nhogefuga\r\n",
+			"get item without flags",
+		},
+		{
+			"get",
+			&storage.Item{Value: []byte("hogefuga"), Flags: 123, CAS: 2},
+			"VALUE hoge 123 8\r\nhogefuga\r\n",
+			"get item with flags omits cas",
+		},
+		{
+			"gets",
+			&storage.Item{Value: []byte("hogefuga"), Flags: 123, CAS: 2},
+			"VALUE hoge 123 8 2\r\nhogefuga\r\n",
+			"gets item with flags replies cas",
+		},
+	}
 
-	p := New()
-	p.Reply(w, []byte("hoge"), []byte("hogefuga"))
-	err := w.Flush()
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := bufio.NewWriter(buf)
 
-	assert.Nil(t, err)
-	assert.Equal(t, "VALUE hoge 0 8\r\nhogefuga\r\n", buf.String())
+			p := New()
+			cmd := &protocol.Command{Name: c.cmd, Keys: [][]byte{[]byte("hoge")}}
+			p.Reply(w, cmd, []byte("hoge"), c.input)
+			err := w.Flush()
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
 }
 
 func TestFinish(t *testing.T) {
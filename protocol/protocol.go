@@ -3,15 +3,33 @@ package protocol
 import (
 	"fmt"
 	"io"
+
+	"github.com/yowcow/goromdb/storage"
 )
 
 // Protocol represents an interface for a protocol
 type Protocol interface {
-	Parse([]byte) ([][]byte, error)
-	Reply(io.Writer, []byte, []byte)
+	Parse([]byte) (*Command, error)
+	Reply(io.Writer, *Command, []byte, *storage.Item)
 	Finish(io.Writer)
 }
 
+// Command represents a parsed command with keys to search, or with arguments for an admin command
+type Command struct {
+	Name string
+	Keys [][]byte
+	Args [][]byte
+}
+
+const (
+	// AdminCommand defines the name of a command to administer loaded data
+	AdminCommand = "admin"
+	// RangeCommand defines the name of a command to find items in a range of keys
+	RangeCommand = "range"
+	// RangeNSCommand defines the name of a command to find items in a range of keys in a namespace
+	RangeNSCommand = "range-ns"
+)
+
 // InvalidCommandError returns an error for invalid command line
 func InvalidCommandError(line []byte) error {
 	return fmt.Errorf("invalid command: %s", string(line))
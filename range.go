@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/yowcow/goromdb/handler"
+	"github.com/yowcow/goromdb/protocol"
+	"github.com/yowcow/goromdb/storage"
+)
+
+// rangeMaxItems defines the max number of items replied by a range command at once
+const rangeMaxItems = 1000
+
+// runRange runs a range command against handler, and writes a page of up to rangeMaxItems items once handler has found them all,
+// so that a slow client never holds storage while being written to.
+// Items are followed by "NEXT <key>" to start next page at if more items exist, and an end of message.
+// Keys in replies and arguments are escaped by escapeKey as in admin commands, so that a key never breaks a line.
+//
+//	range <limit> <from> [<to>]
+//	range-ns <ns> <limit> <from> [<to>]
+func runRange(proto protocol.Protocol, w io.Writer, cmd *protocol.Command, h handler.Handler) {
+	next, err := execRange(proto, w, cmd, h)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if next != nil {
+		fmt.Fprintf(w, "NEXT %s\r\n", escapeKey(next))
+	}
+	proto.Finish(w)
+}
+
+func execRange(proto protocol.Protocol, w io.Writer, cmd *protocol.Command, h handler.Handler) ([]byte, error) {
+	reply := func(key []byte, item *storage.Item) {
+		proto.Reply(w, cmd, []byte(escapeKey(key)), item)
+	}
+	args, err := unescapeArgs(cmd.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Name == protocol.RangeNSCommand {
+		if len(args) < 3 || len(args) > 4 {
+			return nil, &argsError{fmt.Errorf("usage: %s <ns> <limit> <from> [<to>]", cmd.Name)}
+		}
+		rh, ok := h.(handler.NSRangeHandler)
+		if !ok {
+			return nil, storage.NotIterableError()
+		}
+		limit, err := parseLimit(args[1], rangeMaxItems)
+		if err != nil {
+			return nil, err
+		}
+		return rh.RangeNS(args[0], args[2], optionalArg(args, 3), limit, reply)
+	}
+
+	if len(args) < 2 || len(args) > 3 {
+		return nil, &argsError{fmt.Errorf("usage: %s <limit> <from> [<to>]", cmd.Name)}
+	}
+	rh, ok := h.(handler.RangeHandler)
+	if !ok {
+		return nil, storage.NotIterableError()
+	}
+	limit, err := parseLimit(args[0], rangeMaxItems)
+	if err != nil {
+		return nil, err
+	}
+	return rh.Range(args[1], optionalArg(args, 2), limit, reply)
+}
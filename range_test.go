@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/handler/simplehandler"
+	"github.com/yowcow/goromdb/protocol/memcachedprotocol"
+	"github.com/yowcow/goromdb/storage/boltstorage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func TestRunRange(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	proto := memcachedprotocol.New()
+
+	h := simplehandler.New(boltstorage.New("goromdb"), logger)
+	if err := h.Load("_data/store/sample-boltdb.db"); err != nil {
+		t.Fatal(err)
+	}
+	nsh := simplehandler.NewNS(boltstorage.NewNS(), logger)
+	if err := nsh.Load("_data/store/sample-ns-boltdb.db"); err != nil {
+		t.Fatal(err)
+	}
+
+	type Case struct {
+		line     string
+		expected string
+		subtest  string
+	}
+	cases := []Case{
+		{
+			"range 10 b fuga",
+			"VALUE bar 0 7\r\nbar!!!!\r\nVALUE buz 0 8\r\nbuz!!!!!\r\nVALUE foo 0 6\r\nfoo!!!\r\nVALUE fuga 0 6\r\nfuga!!\r\nEND\r\n",
+			"items in range",
+		},
+		{
+			"range 1 foo",
+			"VALUE foo 0 6\r\nfoo!!!\r\nNEXT fuga\r\nEND\r\n",
+			"items up to limit with next key",
+		},
+		{
+			"range 1 x",
+			"END\r\n",
+			"no item in range",
+		},
+		{
+			"range 0 a",
+			"CLIENT_ERROR invalid limit: 0\r\n",
+			"invalid limit fails",
+		},
+		{
+			"range 10",
+			"CLIENT_ERROR usage: range <limit> <from> [<to>]\r\n",
+			"missing from fails",
+		},
+		{
+			"range-ns ns1 10 a",
+			"SERVER_ERROR storage is not iterable\r\n",
+			"range in namespace fails on handler without namespace",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			cmd, err := proto.Parse([]byte(c.line))
+
+			assert.Nil(t, err)
+
+			buf := new(bytes.Buffer)
+			runRange(proto, buf, cmd, h)
+
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+
+	nscases := []Case{
+		{
+			"range-ns ns2 10 a",
+			"VALUE hoge 0 5\r\nhoge2\r\nEND\r\n",
+			"items in range in namespace",
+		},
+		{
+			"range-ns ns3 10 a",
+			"SERVER_ERROR bucket not found error: ns3\r\n",
+			"range in non-existing namespace fails",
+		},
+	}
+
+	for _, c := range nscases {
+		t.Run(c.subtest, func(t *testing.T) {
+			cmd, err := proto.Parse([]byte(c.line))
+
+			assert.Nil(t, err)
+
+			buf := new(bytes.Buffer)
+			runRange(proto, buf, cmd, nsh)
+
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+
+	t.Run("storage without range fails", func(t *testing.T) {
+		cmd, _ := proto.Parse([]byte("range 10 a"))
+		buf := new(bytes.Buffer)
+		runRange(proto, buf, cmd, simplehandler.New(jsonstorage.New(false), logger))
+
+		assert.Equal(t, "SERVER_ERROR storage is not iterable\r\n", buf.String())
+	})
+}
+
+func TestRunRangeEscapesKeys(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db")
+	db, err := bolt.Open(file, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Update(func(tx *bolt.Tx) error {
+		b, _ := tx.CreateBucket([]byte("goromdb"))
+		for _, k := range []string{"a b", "a%c", "a\r\nd"} {
+			b.Put([]byte(k), []byte("v"))
+		}
+		return nil
+	})
+	db.Close()
+
+	h := simplehandler.New(boltstorage.New("goromdb"), log.New(ioutil.Discard, "", 0))
+	if err := h.Load(file); err != nil {
+		t.Fatal(err)
+	}
+	proto := memcachedprotocol.New()
+
+	type Case struct {
+		line     string
+		expected string
+		subtest  string
+	}
+	cases := []Case{
+		{
+			"range 1 a",
+			"VALUE a%0D%0Ad 0 1\r\nv\r\nNEXT a%20b\r\nEND\r\n",
+			"keys in items and next key are escaped",
+		},
+		{
+			"range 10 a%20b a%25c",
+			"VALUE a%20b 0 1\r\nv\r\nVALUE a%25c 0 1\r\nv\r\nEND\r\n",
+			"escaped from and to are unescaped",
+		},
+		{
+			"range 10 a%2",
+			"CLIENT_ERROR invalid escape in argument: a%2\r\n",
+			"invalid escape fails",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			cmd, err := proto.Parse([]byte(c.line))
+
+			assert.Nil(t, err)
+
+			buf := new(bytes.Buffer)
+			runRange(proto, buf, cmd, h)
+
+			assert.Equal(t, c.expected, buf.String())
+		})
+	}
+}
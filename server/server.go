@@ -2,35 +2,81 @@ package server
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
 	"io"
 	"log"
 	"net"
+	"os"
+	"sync"
+	"time"
 )
 
+// shutdownPollInterval defines an interval to check for idle connections to close while shutting down
+const shutdownPollInterval = 100 * time.Millisecond
+
 // OnReadCallbackFunc is a function to be called when a line is read from Conn
 type OnReadCallbackFunc func(net.Conn, []byte, *log.Logger)
 
 // Server represents a server
 type Server struct {
-	network string
-	addr    string
-	logger  *log.Logger
+	network   string
+	addr      string
+	tlsConfig *tls.Config
+	sockMode  os.FileMode
+	logger    *log.Logger
+	mux       *sync.Mutex
+	ln        net.Listener
+	conns     map[net.Conn]bool
+	closing   bool
+}
+
+func newServer(network, addr string, config *tls.Config, mode os.FileMode, logger *log.Logger) *Server {
+	return &Server{network, addr, config, mode, logger, new(sync.Mutex), nil, make(map[net.Conn]bool), false}
 }
 
 // New creates a new server
 func New(network, addr string, logger *log.Logger) *Server {
-	return &Server{network, addr, logger}
+	return newServer(network, addr, nil, 0, logger)
+}
+
+// NewTLS creates a new server accepting TLS connections
+func NewTLS(network, addr string, config *tls.Config, logger *log.Logger) *Server {
+	return newServer(network, addr, config, 0, logger)
+}
+
+// NewUnix creates a new server listening on a unix domain socket with given file mode.
+// Zero mode leaves the socket file mode as created.
+func NewUnix(addr string, mode os.FileMode, logger *log.Logger) *Server {
+	return newServer("unix", addr, nil, mode, logger)
 }
 
-// Start starts a server and spawns a goroutine when a new connection is accepted
+// Start starts a server and spawns a goroutine when a new connection is accepted.
+// Returns nil after server is shut down.
 func (s *Server) Start(callback OnReadCallbackFunc) error {
-	ln, err := net.Listen(s.network, s.addr)
+	ln, err := s.listen()
 	if err != nil {
 		return err
 	}
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
+	}
+
+	s.mux.Lock()
+	if s.closing {
+		s.mux.Unlock()
+		return ln.Close()
+	}
+	s.ln = ln
+	s.mux.Unlock()
+
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if s.isClosing() {
+				return nil
+			}
 			s.logger.Printf("server failed accepting a conn: %s", err.Error())
 		} else {
 			go s.HandleConn(conn, callback)
@@ -38,9 +84,121 @@ func (s *Server) Start(callback OnReadCallbackFunc) error {
 	}
 }
 
+// Shutdown stops accepting connections, and closes open connections once they are idle.
+// Connections still busy when ctx is done are closed anyway.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mux.Lock()
+	s.closing = true
+	if s.ln != nil {
+		s.ln.Close()
+	}
+	s.mux.Unlock()
+
+	tc := time.NewTicker(shutdownPollInterval)
+	defer tc.Stop()
+	for {
+		if s.closeConns(false) == 0 {
+			return nil
+		}
+		select {
+		case <-tc.C:
+		case <-ctx.Done():
+			s.closeConns(true)
+			return ctx.Err()
+		}
+	}
+}
+
+// closeConns closes idle connections, or busy ones too if force, and returns the number of connections left busy
+func (s *Server) closeConns(force bool) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	busy := 0
+	for conn, isBusy := range s.conns {
+		if isBusy && !force {
+			busy++
+			continue
+		}
+		conn.Close()
+		delete(s.conns, conn)
+	}
+	return busy
+}
+
+func (s *Server) isClosing() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.closing
+}
+
+// setConn tracks a connection being busy or idle, and returns false if it is being closed
+func (s *Server) setConn(conn net.Conn, busy bool) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.conns[conn]; !ok && (s.closing || busy) {
+		// Never track a connection accepted while shutting down, nor closed by shutdown
+		return false
+	}
+	s.conns[conn] = busy
+	return true
+}
+
+func (s *Server) removeConn(conn net.Conn) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.conns, conn)
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	if s.network != "unix" {
+		return net.Listen(s.network, s.addr)
+	}
+	if err := removeStaleSocket(s.addr); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen(s.network, s.addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.sockMode != 0 {
+		if err := os.Chmod(s.addr, s.sockMode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes a socket file left by a process no longer listening on it
+func removeStaleSocket(file string) error {
+	fi, err := os.Lstat(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("path '%s' exists and not socket", file)
+	}
+	if conn, err := net.Dial("unix", file); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket '%s' is in use", file)
+	}
+	return os.Remove(file)
+}
+
 // HandleConn handles a net.Conn
 func (s *Server) HandleConn(conn net.Conn, callback OnReadCallbackFunc) {
-	defer conn.Close()
+	defer func() {
+		s.removeConn(conn)
+		conn.Close()
+	}()
+	if !s.setConn(conn, false) {
+		return
+	}
 	r := bufio.NewReader(conn)
 	for {
 		line, _, err := r.ReadLine()
@@ -48,10 +206,16 @@ func (s *Server) HandleConn(conn net.Conn, callback OnReadCallbackFunc) {
 			return
 		}
 		if err != nil {
-			s.logger.Printf("server failed reading a line: %s", err)
+			if !s.isClosing() {
+				s.logger.Printf("server failed reading a line: %s", err)
+			}
 			return
 		}
 
+		if !s.setConn(conn, true) {
+			return
+		}
 		callback(conn, line, s.logger)
+		s.setConn(conn, false)
 	}
 }
@@ -1,12 +1,15 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yowcow/goromdb/testutil"
@@ -69,3 +72,201 @@ func TestHandleConn(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+func TestListenOnUnixSocket(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	sock := filepath.Join(dir, "test.sock")
+	svr := NewUnix(sock, 0600, logger)
+
+	ln, err := svr.listen()
+
+	assert.Nil(t, err)
+
+	fi, err := os.Stat(sock)
+
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	_, err = svr.listen()
+
+	assert.NotNil(t, err, "socket in use is not removed")
+
+	ln.Close()
+}
+
+func TestListenRemovesStaleSocket(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	sock := filepath.Join(dir, "test.sock")
+
+	// leave a socket file without anybody listening
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		panic(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	_, err = os.Stat(sock)
+
+	assert.Nil(t, err)
+
+	svr := NewUnix(sock, 0, logger)
+	ln, err = svr.listen()
+
+	assert.Nil(t, err)
+
+	ln.Close()
+}
+
+func TestListenFailsOnNonSocketFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	file := filepath.Join(dir, "test.sock")
+	testutil.CopyFile(file, "server.go")
+
+	svr := NewUnix(file, 0, logger)
+	_, err := svr.listen()
+
+	assert.NotNil(t, err)
+
+	_, err = os.Stat(file)
+
+	assert.Nil(t, err)
+}
+
+// startServer starts a server in background, and returns a channel receiving what Start returns
+func startServer(svr *Server, callback OnReadCallbackFunc) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- svr.Start(callback)
+	}()
+	return errs
+}
+
+func dial(sock string) net.Conn {
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			return conn
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	panic("server never started listening")
+}
+
+func TestShutdownClosesIdleConns(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logger := log.New(new(bytes.Buffer), "", 0)
+	sock := filepath.Join(dir, "test.sock")
+	svr := NewUnix(sock, 0, logger)
+	errs := startServer(svr, func(conn net.Conn, line []byte, logger *log.Logger) {
+		conn.Write(append(line, '\n'))
+	})
+
+	conn := dial(sock)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("hello\r\n"))
+	line, _ := r.ReadString('\n')
+
+	assert.Equal(t, "hello\n", line)
+
+	err := svr.Shutdown(context.Background())
+
+	assert.Nil(t, err)
+	assert.Nil(t, <-errs)
+
+	_, err = r.ReadString('\n')
+
+	assert.NotNil(t, err, "idle conn is closed")
+
+	_, err = net.Dial("unix", sock)
+
+	assert.NotNil(t, err, "server stops listening")
+}
+
+func TestShutdownWaitsForBusyConns(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logger := log.New(new(bytes.Buffer), "", 0)
+	sock := filepath.Join(dir, "test.sock")
+	svr := NewUnix(sock, 0, logger)
+	busy := make(chan bool)
+	release := make(chan bool)
+	errs := startServer(svr, func(conn net.Conn, line []byte, logger *log.Logger) {
+		close(busy)
+		<-release
+		conn.Write(append(line, '\n'))
+	})
+
+	conn := dial(sock)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("hello\r\n"))
+	<-busy
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- svr.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown returned while a conn is busy")
+	case <-time.After(3 * shutdownPollInterval):
+	}
+
+	close(release)
+	line, _ := r.ReadString('\n')
+
+	assert.Equal(t, "hello\n", line)
+	assert.Nil(t, <-shutdown)
+	assert.Nil(t, <-errs)
+}
+
+func TestShutdownClosesBusyConnsAtDeadline(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logger := log.New(new(bytes.Buffer), "", 0)
+	sock := filepath.Join(dir, "test.sock")
+	svr := NewUnix(sock, 0, logger)
+	busy := make(chan bool)
+	release := make(chan bool)
+	defer close(release)
+	startServer(svr, func(conn net.Conn, line []byte, logger *log.Logger) {
+		close(busy)
+		<-release
+	})
+
+	conn := dial(sock)
+	defer conn.Close()
+	conn.Write([]byte("hello\r\n"))
+	<-busy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*shutdownPollInterval)
+	defer cancel()
+	err := svr.Shutdown(ctx)
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	_, err = bufio.NewReader(conn).ReadString('\n')
+
+	assert.NotNil(t, err, "busy conn is closed")
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// CertReloader represents a TLS certificate keypair to be reloaded whenever its files change
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	interval int
+	cert     *atomic.Value
+	modTime  time.Time
+	logger   *log.Logger
+}
+
+// NewCertReloader loads a certificate keypair, and returns a CertReloader
+func NewCertReloader(certFile, keyFile string, interval int, logger *log.Logger) (*CertReloader, error) {
+	r := &CertReloader{certFile, keyFile, interval, new(atomic.Value), time.Time{}, logger}
+	modTime, err := r.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start starts a goroutine checking for certificate changes, and returns a channel to be closed when finished
+func (r *CertReloader) Start(ctx context.Context) <-chan bool {
+	done := make(chan bool)
+	go r.watch(ctx, done)
+	return done
+}
+
+func (r *CertReloader) watch(ctx context.Context, done chan<- bool) {
+	d := time.Duration(r.interval) * time.Millisecond
+	tc := time.NewTicker(d)
+	defer func() {
+		r.logger.Printf("certreloader finished watching for file: %s", r.certFile)
+		tc.Stop()
+		close(done)
+	}()
+	r.logger.Printf("certreloader started watching for file: %s", r.certFile)
+	for {
+		select {
+		case <-tc.C:
+			modTime, err := r.lastModified()
+			if err != nil {
+				r.logger.Printf("certreloader failed checking certificate: %s", err.Error())
+				continue
+			}
+			if !modTime.After(r.modTime) {
+				continue
+			}
+			// A failed load is retried on next tick, since cert and key files may be in the middle of being replaced
+			if err := r.load(modTime); err != nil {
+				r.logger.Printf("certreloader failed reloading certificate: %s", err.Error())
+			} else {
+				r.logger.Printf("certreloader reloaded certificate from '%s'", r.certFile)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *CertReloader) lastModified() (time.Time, error) {
+	var modTime time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return modTime, err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+func (r *CertReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	r.modTime = modTime
+	return nil
+}
+
+// GetCertificate returns currently loaded certificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load().(*tls.Certificate), nil
+}
+
+// NewTLSConfig creates a TLS config serving certificate from given reloader.
+// When clientCAFile is given, client certificates are required and verified against the CAs in the file.
+func NewTLSConfig(r *CertReloader, clientCAFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		GetCertificate: r.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if ok := pool.AppendCertsFromPEM(pem); !ok {
+			return nil, fmt.Errorf("no valid certificate found in '%s'", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// createCert creates a certificate signed by given parent, or a self-signed CA certificate if parent is nil
+func createCert(cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		panic(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{cn},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return &testCert{cert, key, der}
+}
+
+func (c *testCert) writeFiles(certFile, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		panic(err)
+	}
+	writePEM(certFile, "CERTIFICATE", c.der)
+	writePEM(keyFile, "EC PRIVATE KEY", keyDER)
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func writePEM(file, typ string, der []byte) {
+	fo, err := os.Create(file)
+	if err != nil {
+		panic(err)
+	}
+	defer fo.Close()
+	if err := pem.Encode(fo, &pem.Block{Type: typ, Bytes: der}); err != nil {
+		panic(err)
+	}
+}
+
+func TestNewCertReloader(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	_, err := NewCertReloader(certFile, keyFile, 1000, logger)
+
+	assert.NotNil(t, err)
+
+	createCert("localhost", nil).writeFiles(certFile, keyFile)
+	r, err := NewCertReloader(certFile, keyFile, 1000, logger)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, r)
+}
+
+func TestCertReloaderReloadsOnChange(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	cert1 := createCert("localhost", nil)
+	cert1.writeFiles(certFile, keyFile)
+
+	r, err := NewCertReloader(certFile, keyFile, 10, logger)
+	if err != nil {
+		t.Fatal("failed creating a cert reloader", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := r.Start(ctx)
+
+	cert2 := createCert("localhost", nil)
+	cert2.writeFiles(certFile, keyFile)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	<-done
+
+	actual, err := r.GetCertificate(nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, cert2.der, actual.Certificate[0])
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	createCert("localhost", nil).writeFiles(certFile, keyFile)
+	r, _ := NewCertReloader(certFile, keyFile, 1000, logger)
+
+	type Case struct {
+		subtest      string
+		clientCAFile string
+		expectError  bool
+		expectedAuth tls.ClientAuthType
+	}
+	cases := []Case{
+		{
+			"without client CA",
+			"",
+			false,
+			tls.NoClientCert,
+		},
+		{
+			"with non-existing client CA",
+			caFile,
+			true,
+			tls.NoClientCert,
+		},
+		{
+			"with invalid client CA",
+			keyFile,
+			true,
+			tls.NoClientCert,
+		},
+		{
+			"with valid client CA",
+			certFile,
+			false,
+			tls.RequireAndVerifyClientCert,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			config, err := NewTLSConfig(r, c.clientCAFile)
+
+			assert.Equal(t, c.expectError, err != nil)
+			if !c.expectError {
+				assert.Equal(t, c.expectedAuth, config.ClientAuth)
+			}
+		})
+	}
+}
+
+func TestHandleConnWithMutualTLS(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	logbuf := new(bytes.Buffer)
+	logger := log.New(logbuf, "", 0)
+
+	ca := createCert("goromdb-ca", nil)
+	caFile := filepath.Join(dir, "ca.crt")
+	ca.writeFiles(caFile, filepath.Join(dir, "ca.key"))
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	createCert("localhost", ca).writeFiles(certFile, keyFile)
+
+	r, _ := NewCertReloader(certFile, keyFile, 1000, logger)
+	config, _ := NewTLSConfig(r, caFile)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.cert)
+
+	type Case struct {
+		subtest     string
+		clientCerts []tls.Certificate
+		expectError bool
+	}
+	cases := []Case{
+		{
+			"client with certificate signed by CA succeeds",
+			[]tls.Certificate{createCert("client", ca).tlsCertificate()},
+			false,
+		},
+		{
+			"client with self-signed certificate fails",
+			[]tls.Certificate{createCert("client", nil).tlsCertificate()},
+			true,
+		},
+		{
+			"client without certificate fails",
+			nil,
+			true,
+		},
+	}
+
+	sock := filepath.Join(dir, "test.sock")
+	svr := NewTLS("unix", sock, config, logger)
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			lines := make(chan []byte, 1)
+
+			ln, err := net.Listen("unix", sock)
+			if err != nil {
+				panic(err)
+			}
+			ln = tls.NewListener(ln, config)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				svr.HandleConn(conn, OnReadCallbackFunc(func(conn net.Conn, line []byte, logger *log.Logger) {
+					lines <- line
+					conn.Write([]byte("OK\r\n"))
+				}))
+			}()
+
+			conn, err := tls.Dial("unix", sock, &tls.Config{
+				ServerName:   "localhost",
+				RootCAs:      rootCAs,
+				Certificates: c.clientCerts,
+			})
+			if err == nil {
+				_, err = conn.Write([]byte("hello world\r\n"))
+			}
+			if err == nil {
+				// Rejected client certificate is only reported on reading since TLS 1.3
+				_, err = conn.Read(make([]byte, 4))
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			ln.Close()
+
+			assert.Equal(t, c.expectError, err != nil)
+			if !c.expectError {
+				assert.Equal(t, []byte("hello world"), <-lines)
+			}
+		})
+	}
+}
@@ -1,27 +1,134 @@
 package bdbstorage
 
 import (
+	"bytes"
+	"sort"
+
 	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/nskey"
 )
 
 var (
-	_ storage.NSStorage = (*NSStorage)(nil)
+	_ storage.NSStorage       = (*NSStorage)(nil)
+	_ storage.NSIterable      = (*NSStorage)(nil)
+	_ storage.NSRangeIterable = (*NSStorage)(nil)
 )
 
-// NSStorage represents a namespaced BDB storage
+// NSStorage represents a namespaced BDB storage, having namespaces and keys encoded into single keys
 type NSStorage struct {
 	Storage
+	enc nskey.Encoding
 }
 
-// NewNS creates and returns a storage
-func NewNS() *NSStorage {
-	return &NSStorage{*New()}
+// NewNS creates and returns a storage with given encoding of namespace and key.
+// nskey.Concat reads data by older versions, which concatenated namespace and key.
+func NewNS(enc nskey.Encoding) *NSStorage {
+	return &NSStorage{*New(), enc}
 }
 
 // GetNS finds a given ns+key in db, and returns its value
 func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
-	fullKey := make([]byte, 0, len(ns)+len(key))
-	fullKey = append(fullKey, ns...)
-	fullKey = append(fullKey, key...)
+	fullKey, err := s.encode(ns, key)
+	if err != nil {
+		return nil, err
+	}
 	return s.Get(fullKey)
 }
+
+// RangeNS calls fn with items having keys from "from" to "to" in namespace in db in order, until fn returns false
+func (s *NSStorage) RangeNS(ns, from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	prefix, err := s.encode(ns, nil)
+	if err != nil {
+		return err
+	}
+	start, err := s.encode(ns, from)
+	if err != nil {
+		return err
+	}
+	return s.walk(start, func(k, v []byte) bool {
+		if !bytes.HasPrefix(k, prefix) {
+			return false
+		}
+		key := k[len(prefix):]
+		if len(to) > 0 && bytes.Compare(key, to) > 0 {
+			return false
+		}
+		return fn(key, &storage.Item{Value: v})
+	})
+}
+
+// Namespaces decodes namespaces of all keys in db, and returns them in sorted order
+func (s *NSStorage) Namespaces() ([][]byte, error) {
+	found := make(map[string]bool)
+	var broken []byte
+	err := s.walk(nil, func(k, v []byte) bool {
+		ns, _, ok := s.enc.Decode(k)
+		if !ok {
+			broken = k
+			return false
+		}
+		found[string(ns)] = true
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if broken != nil {
+		return nil, storage.InternalError("couldn't decode namespace of key: " + string(broken))
+	}
+
+	names := make([]string, 0, len(found))
+	for ns := range found {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+
+	nss := make([][]byte, len(names))
+	for i, ns := range names {
+		nss[i] = []byte(ns)
+	}
+	return nss, nil
+}
+
+// KeysNS finds keys having prefix in namespace in db, and returns up to limit keys starting at start
+func (s *NSStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	nsPrefix, err := s.encode(ns, nil)
+	if err != nil {
+		return nil, err
+	}
+	fullPrefix, err := s.encode(ns, prefix)
+	if err != nil {
+		return nil, err
+	}
+	fullStart, err := s.encode(ns, start)
+	if err != nil {
+		return nil, err
+	}
+	fullKeys, err := s.Keys(fullPrefix, fullStart, limit)
+	if err != nil {
+		return nil, err
+	}
+	n := len(nsPrefix)
+	for i, k := range fullKeys {
+		fullKeys[i] = k[n:]
+	}
+	return fullKeys, nil
+}
+
+// CountNS counts keys having prefix in namespace in db
+func (s *NSStorage) CountNS(ns, prefix []byte) (int, error) {
+	fullPrefix, err := s.encode(ns, prefix)
+	if err != nil {
+		return 0, err
+	}
+	return s.Count(fullPrefix)
+}
+
+// encode encodes ns and key into a key in db, and fails if ns can't be told apart from others by encoding
+func (s *NSStorage) encode(ns, key []byte) ([]byte, error) {
+	fullKey, err := s.enc.Encode(ns, key)
+	if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+	return fullKey, nil
+}
@@ -4,14 +4,18 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/nskey"
 )
 
+var sampleNSDBFile = "../../_data/store/sample-ns-bdb.db"
+
 func TestNewNS(t *testing.T) {
-	NewNS()
+	NewNS(nskey.Concat)
 }
 
 func TestGetNS(t *testing.T) {
-	s := NewNS()
+	s := NewNS(nskey.Concat)
 	err := s.Load(sampleDBFile)
 
 	assert.Nil(t, err)
@@ -52,3 +56,92 @@ func TestGetNS(t *testing.T) {
 		})
 	}
 }
+
+func TestRangeNS(t *testing.T) {
+	s := NewNS(nskey.Concat)
+	s.Load(sampleDBFile)
+
+	var actual []string
+	err := s.RangeNS([]byte("f"), []byte("oo"), nil, func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"oo=foo!!!", "uga=fuga!!"}, actual)
+}
+
+func TestNamespacesAndKeysNS(t *testing.T) {
+	s := NewNS(nskey.LengthPrefix)
+	err := s.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	v, err := s.GetNS([]byte("ns2"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge2"), v)
+
+	names, err := s.Namespaces()
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("ns1"), []byte("ns2")}, names)
+
+	keys, err := s.KeysNS([]byte("ns1"), []byte("h"), nil, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("hoge")}, keys)
+
+	n, err := s.CountNS([]byte("ns2"), nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = s.CountNS([]byte("ns3"), nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	var actual []string
+	err = s.RangeNS([]byte("ns1"), nil, nil, func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hoge=hoge1"}, actual)
+}
+
+func TestNSFailsWithSeparatorInNamespace(t *testing.T) {
+	s := NewNS(nskey.Separator(":"))
+	s.Load(sampleNSDBFile)
+
+	_, err := s.GetNS([]byte("ns1:hoge"), nil)
+
+	assert.NotNil(t, err)
+
+	_, err = s.KeysNS([]byte("ns1:hoge"), nil, nil, 0)
+
+	assert.NotNil(t, err)
+
+	_, err = s.CountNS([]byte("ns1:hoge"), nil)
+
+	assert.NotNil(t, err)
+
+	err = s.RangeNS([]byte("ns1:hoge"), nil, nil, func(key []byte, item *storage.Item) bool {
+		return true
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestNamespacesFailsWithConcat(t *testing.T) {
+	s := NewNS(nskey.Concat)
+	err := s.Load(sampleDBFile)
+
+	assert.Nil(t, err)
+
+	_, err = s.Namespaces()
+
+	assert.NotNil(t, err)
+}
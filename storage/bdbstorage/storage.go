@@ -1,6 +1,9 @@
 package bdbstorage
 
 import (
+	"bytes"
+	"io"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -9,18 +12,23 @@ import (
 )
 
 var (
-	_ storage.Storage = (*Storage)(nil)
+	_ storage.Storage       = (*Storage)(nil)
+	_ storage.Iterable      = (*Storage)(nil)
+	_ storage.RangeIterable = (*Storage)(nil)
+	_ storage.Generational  = (*Storage)(nil)
+	_ io.Closer             = (*Storage)(nil)
 )
 
 // Storage represents a BDB storage
 type Storage struct {
-	db  *atomic.Value
-	mux *sync.RWMutex
+	db         *atomic.Value
+	mux        *sync.RWMutex
+	generation *uint64
 }
 
 // New creates and returns a storage
 func New() *Storage {
-	return &Storage{new(atomic.Value), new(sync.RWMutex)}
+	return &Storage{new(atomic.Value), new(sync.RWMutex), new(uint64)}
 }
 
 // Load loads a new db handle into storage, and closes old db handle if exists
@@ -35,12 +43,31 @@ func (s *Storage) Load(file string) error {
 
 	oldDB := s.getDB()
 	s.db.Store(newDB)
+	atomic.AddUint64(s.generation, 1)
 	if oldDB != nil {
 		oldDB.Close(0)
 	}
 	return nil
 }
 
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+// Close waits for reads in progress, and closes db handle if exists
+func (s *Storage) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldDB := s.getDB()
+	if oldDB == nil {
+		return nil
+	}
+	s.db.Store((*bdb.BerkeleyDB)(nil))
+	return oldDB.Close(0)
+}
+
 func (s *Storage) getDB() *bdb.BerkeleyDB {
 	if ptr := s.db.Load(); ptr != nil {
 		return ptr.(*bdb.BerkeleyDB)
@@ -69,3 +96,86 @@ func (s *Storage) Get(key []byte) ([]byte, error) {
 
 	return v, nil
 }
+
+// Keys finds keys having prefix in db, and returns up to limit keys starting at start
+func (s *Storage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	seek := prefix
+	if bytes.Compare(start, prefix) > 0 {
+		seek = start
+	}
+	var keys [][]byte
+	err := s.walk(seek, func(k, v []byte) bool {
+		if !bytes.HasPrefix(k, prefix) || (limit > 0 && len(keys) >= limit) {
+			return false
+		}
+		keys = append(keys, k)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Count counts keys having prefix in db
+func (s *Storage) Count(prefix []byte) (int, error) {
+	n := 0
+	err := s.walk(prefix, func(k, v []byte) bool {
+		if !bytes.HasPrefix(k, prefix) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n, err
+}
+
+// Range calls fn with items having keys from "from" to "to" in db in order, until fn returns false
+func (s *Storage) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return s.walk(from, func(k, v []byte) bool {
+		if len(to) > 0 && bytes.Compare(k, to) > 0 {
+			return false
+		}
+		return fn(k, &storage.Item{Value: v})
+	})
+}
+
+// walk calls fn with keys and values in order starting at start, until fn returns false.
+// Cursor seeks to the smallest key not less than start, since db is a BTree where keys are sorted.
+func (s *Storage) walk(start []byte, fn func(k, v []byte) bool) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return storage.InternalError("couldn't load db")
+	}
+
+	cursor, err := db.NewCursor(bdb.NoTxn, 0)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	var k, v []byte
+	if len(start) == 0 {
+		k, v, err = cursor.First()
+	} else {
+		k, v, err = cursor.SetRange(start)
+	}
+	for ; err == nil; k, v, err = cursor.Next() {
+		if !fn(k, v) {
+			return nil
+		}
+	}
+	if isNotFound(err) {
+		// Cursor returns DB_NOTFOUND at the end of db
+		return nil
+	}
+	return storage.InternalError(err.Error())
+}
+
+// isNotFound returns whether or not err is DB_NOTFOUND, telling that no more key exists
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), "DB_NOTFOUND")
+}
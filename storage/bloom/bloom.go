@@ -0,0 +1,293 @@
+package bloom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+
+	"github.com/cespare/xxhash"
+	"github.com/yowcow/goromdb/storage/nskey"
+)
+
+// Suffix defines a suffix of a sidecar file to a database file
+const Suffix = ".bloom"
+
+var magic = []byte("GRBF\x03")
+
+// maxBits limits the size of a filter to read
+const maxBits = 1 << 40
+
+// chunkWords limits words of a filter body allocated at once, so that a truncated body fails before allocating its size
+const chunkWords = 1 << 16
+
+// headSize defines bytes of each of the head and the tail of a database file to take a sum of
+const headSize = 64 << 10
+
+// maxBucketLen limits the length of a bucket name or a separator to read
+const maxBucketLen = 1 << 16
+
+// Scope represents a database file and keys a filter is built for, so that a sidecar file is never used for another database
+type Scope struct {
+	Sum       uint64 // xxhash of the database file, by FileSum
+	Bucket    string // bucket of keys, or empty for namespaced keys in all buckets
+	Separator string // separator joining names of nested buckets into a namespace, or empty for top level buckets only
+}
+
+// Filter represents a Bloom filter
+type Filter struct {
+	k     uint32
+	m     uint64
+	bits  []uint64
+	scope Scope
+}
+
+// New creates a filter sized for n keys with given false positive rate
+func New(n int, fpRate float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return newFilter(k, m)
+}
+
+func newFilter(k uint32, m uint64) *Filter {
+	return &Filter{k, m, make([]uint64, (m+63)/64), Scope{}}
+}
+
+// FileSum returns xxhash of the size, the head and the tail of a database file, to tell the database a filter is built for.
+// This reads a few pages however large the file is, and a database like BoltDB rewrites its meta pages in the head at every commit.
+func FileSum(file string) (uint64, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer fi.Close()
+
+	st, err := fi.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := st.Size()
+
+	h := xxhash.New()
+	if err := binary.Write(h, binary.LittleEndian, size); err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(h, io.LimitReader(fi, headSize)); err != nil {
+		return 0, err
+	}
+	if size > 2*headSize {
+		if _, err := fi.Seek(size-headSize, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := io.Copy(h, fi); err != nil {
+		return 0, err
+	}
+	return h.Sum64(), nil
+}
+
+// SetScope sets what filter is built for, to be written with filter
+func (f *Filter) SetScope(scope Scope) {
+	f.scope = scope
+}
+
+// Scope returns what filter is built for
+func (f *Filter) Scope() Scope {
+	return f.scope
+}
+
+// hashes returns two hashes of given key to derive k bit positions from
+func hashes(key []byte) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write(key)
+	h1 := h.Sum64()
+
+	// splitmix64 finalizer to get an independent second hash
+	h2 := h1 + 0x9e3779b97f4a7c15
+	h2 = (h2 ^ (h2 >> 30)) * 0xbf58476d1ce4e5b9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94d049bb133111eb
+	h2 = h2 ^ (h2 >> 31)
+	return h1, h2 | 1
+}
+
+// Add adds given key to filter
+func (f *Filter) Add(key []byte) {
+	h1, h2 := hashes(key)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// Test returns false if given key is definitely not in filter, or true if it may be
+func (f *Filter) Test(key []byte) bool {
+	h1, h2 := hashes(key)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NSKey returns a key to add and test a namespaced key with, unique to given ns+key
+func NSKey(ns, key []byte) []byte {
+	fullKey, _ := nskey.LengthPrefix.Encode(ns, key) // length prefix takes any namespace
+	return fullKey
+}
+
+// WriteTo writes filter in binary into writer
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var data = []interface{}{
+		magic,
+		f.scope.Sum,
+		uint32(len(f.scope.Bucket)),
+		[]byte(f.scope.Bucket),
+		uint32(len(f.scope.Separator)),
+		[]byte(f.scope.Separator),
+		f.k,
+		f.m,
+		f.bits,
+	}
+	for _, v := range data {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return 0, fmt.Errorf("failed writing bloom filter: %s", err.Error())
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+	return int64(len(magic) + 8 + 4 + len(f.scope.Bucket) + 4 + len(f.scope.Separator) + 4 + 8 + 8*len(f.bits)), nil
+}
+
+// Read reads a filter in binary from reader
+func Read(r io.Reader) (*Filter, error) {
+	return read(r, -1)
+}
+
+// read reads a filter in binary from reader, failing before reading body if size is known and doesn't fit the header
+func read(r io.Reader, size int64) (*Filter, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed reading bloom filter header: %s", err.Error())
+	}
+	if !bytes.Equal(header, magic) {
+		return nil, fmt.Errorf("invalid bloom filter header")
+	}
+
+	var sum uint64
+	if err := binary.Read(br, binary.LittleEndian, &sum); err != nil {
+		return nil, fmt.Errorf("failed reading bloom filter header: %s", err.Error())
+	}
+	bucket, err := readString(br, "bucket")
+	if err != nil {
+		return nil, err
+	}
+	separator, err := readString(br, "separator")
+	if err != nil {
+		return nil, err
+	}
+
+	var k uint32
+	var m uint64
+	for _, v := range []interface{}{&k, &m} {
+		if err := binary.Read(br, binary.LittleEndian, v); err != nil {
+			return nil, fmt.Errorf("failed reading bloom filter header: %s", err.Error())
+		}
+	}
+	if k < 1 || m < 1 || m > maxBits {
+		return nil, fmt.Errorf("invalid bloom filter size: k=%d, m=%d", k, m)
+	}
+
+	words := (m + 63) / 64
+	headerLen := int64(len(magic) + 8 + 4 + len(bucket) + 4 + len(separator) + 4 + 8)
+	if size >= 0 && size-headerLen != int64(8*words) {
+		return nil, fmt.Errorf("invalid bloom filter size: m=%d for %d bytes", m, size)
+	}
+
+	bits, err := readBits(br, words)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{k, m, bits, Scope{sum, bucket, separator}}, nil
+}
+
+// readBits reads words of a filter body chunk by chunk, so that allocation never goes far beyond bytes actually read
+func readBits(r io.Reader, words uint64) ([]uint64, error) {
+	bits := make([]uint64, 0, minWords(words, chunkWords))
+	for uint64(len(bits)) < words {
+		chunk := make([]uint64, minWords(words-uint64(len(bits)), chunkWords))
+		if err := binary.Read(r, binary.LittleEndian, chunk); err != nil {
+			return nil, fmt.Errorf("failed reading bloom filter body: %s", err.Error())
+		}
+		bits = append(bits, chunk...)
+	}
+	return bits, nil
+}
+
+func minWords(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// readString reads a length-prefixed string in a header
+func readString(r io.Reader, name string) (string, error) {
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return "", fmt.Errorf("failed reading bloom filter header: %s", err.Error())
+	}
+	if n > maxBucketLen {
+		return "", fmt.Errorf("invalid bloom filter %s length: %d", name, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf("failed reading bloom filter header: %s", err.Error())
+	}
+	return string(b), nil
+}
+
+// ReadFile reads a filter from file
+func ReadFile(file string) (*Filter, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	st, err := fi.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return read(fi, st.Size())
+}
+
+// WriteFile writes filter into file
+func (f *Filter) WriteFile(file string) error {
+	fo, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteTo(fo); err != nil {
+		fo.Close()
+		return err
+	}
+	return fo.Close()
+}
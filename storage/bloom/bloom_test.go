@@ -0,0 +1,192 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func TestNew(t *testing.T) {
+	type Case struct {
+		n         int
+		fpRate    float64
+		expectedK uint32
+		expectedM uint64
+		subtest   string
+	}
+	cases := []Case{
+		{0, 0.01, 44, 64, "zero keys gets minimum size"},
+		{1000, 0.01, 7, 9586, "1000 keys with 1% false positive rate"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			f := New(c.n, c.fpRate)
+
+			assert.Equal(t, c.expectedK, f.k)
+			assert.Equal(t, c.expectedM, f.m)
+		})
+	}
+}
+
+func TestAddAndTest(t *testing.T) {
+	f := New(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add([]byte(fmt.Sprintf("key-%d", i)))
+	}
+
+	for i := 0; i < 10000; i++ {
+		if !f.Test([]byte(fmt.Sprintf("key-%d", i))) {
+			t.Fatalf("expected key-%d to be found", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Test([]byte(fmt.Sprintf("non-key-%d", i))) {
+			falsePositives++
+		}
+	}
+
+	assert.True(t, falsePositives < 200, "false positives: %d", falsePositives)
+}
+
+func TestNSKey(t *testing.T) {
+	assert.NotEqual(t, NSKey([]byte("ab"), []byte("c")), NSKey([]byte("a"), []byte("bc")))
+	assert.Equal(t, []byte("\x02abc"), NSKey([]byte("ab"), []byte("c")))
+}
+
+func TestWriteToAndRead(t *testing.T) {
+	f := New(100, 0.01)
+	f.Add([]byte("hoge"))
+	f.SetScope(Scope{12345, "goromdb", "/"})
+
+	buf := new(bytes.Buffer)
+	n, err := f.WriteTo(buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	actual, err := Read(buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, f, actual)
+	assert.Equal(t, Scope{12345, "goromdb", "/"}, actual.Scope())
+	assert.True(t, actual.Test([]byte("hoge")))
+}
+
+func TestFileSum(t *testing.T) {
+	sum, err := FileSum("bloom.go")
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, uint64(0), sum)
+
+	other, _ := FileSum("bloom_test.go")
+
+	assert.NotEqual(t, sum, other)
+
+	_, err = FileSum("hoge.go")
+
+	assert.NotNil(t, err)
+}
+
+func TestFileSumOfLargeFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db")
+	data := make([]byte, 4*headSize)
+	ioutil.WriteFile(file, data, 0644)
+	sum, _ := FileSum(file)
+
+	type Case struct {
+		offset   int
+		expected bool
+		subtest  string
+	}
+	cases := []Case{
+		{0, false, "change in head changes sum"},
+		{2 * headSize, true, "change in middle is not read"},
+		{4*headSize - 1, false, "change in tail changes sum"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			changed := append([]byte{}, data...)
+			changed[c.offset] = 1
+			ioutil.WriteFile(file, changed, 0644)
+			actual, err := FileSum(file)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, sum == actual)
+		})
+	}
+
+	ioutil.WriteFile(file, data[:len(data)-1], 0644)
+	actual, _ := FileSum(file)
+
+	assert.NotEqual(t, sum, actual)
+}
+
+func TestRead(t *testing.T) {
+	type Case struct {
+		input   []byte
+		subtest string
+	}
+	cases := []Case{
+		{[]byte(""), "empty input fails"},
+		{[]byte("HOGE\x02"), "invalid header fails"},
+		{[]byte("GRBF\x01\x01\x00\x00\x00\x40\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "filter without scope fails"},
+		{[]byte("GRBF\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x40\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "filter without separator fails"},
+		{[]byte("GRBF\x03\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00h"), "truncated bucket fails"},
+		{[]byte("GRBF\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00/"), "truncated separator fails"},
+		{[]byte("GRBF\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "truncated header fails"},
+		{[]byte("GRBF\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "zero size fails"},
+		{[]byte("GRBF\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x40\x00\x00\x00\x00\x00\x00\x00"), "truncated body fails"},
+		{[]byte("GRBF\x03\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "huge size with truncated body fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			f, err := Read(bytes.NewReader(c.input))
+
+			assert.Nil(t, f)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestWriteFileAndReadFile(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db"+Suffix)
+	f := New(100, 0.01)
+	f.Add([]byte("hoge"))
+
+	err := f.WriteFile(file)
+
+	assert.Nil(t, err)
+
+	actual, err := ReadFile(file)
+
+	assert.Nil(t, err)
+	assert.Equal(t, f, actual)
+
+	_, err = ReadFile(file + ".hoge")
+
+	assert.NotNil(t, err)
+
+	st, _ := os.Stat(file)
+	os.Truncate(file, st.Size()-8)
+	actual, err = ReadFile(file)
+
+	assert.Nil(t, actual)
+	assert.NotNil(t, err)
+}
@@ -4,11 +4,15 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/boltdb/bolt"
 	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/bloom"
 )
 
 var (
-	_ storage.NSStorage = (*NSStorage)(nil)
+	_ storage.NSStorage       = (*NSStorage)(nil)
+	_ storage.NSIterable      = (*NSStorage)(nil)
+	_ storage.NSRangeIterable = (*NSStorage)(nil)
 )
 
 // NSStorage represents a namespaced BoldDB storage
@@ -18,7 +22,14 @@ type NSStorage struct {
 
 // NewNS creates and returns a storage
 func NewNS() *NSStorage {
-	return &NSStorage{Storage{new(atomic.Value), nil, new(sync.RWMutex)}}
+	return &NSStorage{Storage{new(atomic.Value), nil, new(sync.RWMutex), 0, nil, new(uint64)}}
+}
+
+// UseBucketPath makes storage resolve a namespace as a path of nested buckets separated by sep, like "tenant/dataset".
+// Loading a file fails if a bucket has a name containing sep, since it can't be reached by a path.
+// This must be called before loading any data.
+func (s *NSStorage) UseBucketPath(sep string) {
+	s.separator = []byte(sep)
 }
 
 // GetNS finds a given bucket and key in db, and returns its value
@@ -30,10 +41,72 @@ func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
+	h := s.getHandle()
+	if h == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+	if h.filter != nil && !h.filter.Test(bloom.NSKey(ns, key)) {
+		return nil, storage.KeyNotFoundError(key)
+	}
+
+	return s.getFromBucket(h.db, ns, key)
+}
+
+// Namespaces returns names of buckets in db, including paths to nested buckets if bucket path is used
+func (s *NSStorage) Namespaces() ([][]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
 	db := s.getDB()
 	if db == nil {
 		return nil, storage.InternalError("couldn't load db")
 	}
 
-	return getFromBucket(db, ns, key)
+	var names [][]byte
+	err := db.View(func(tx *bolt.Tx) error {
+		return s.forEachNS(tx, func(name []byte, b *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// KeysNS finds keys having prefix in a given bucket, and returns up to limit keys starting at start
+func (s *NSStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+	return s.keysInBucket(db, ns, prefix, start, limit)
+}
+
+// CountNS counts keys having prefix in a given bucket
+func (s *NSStorage) CountNS(ns, prefix []byte) (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return 0, storage.InternalError("couldn't load db")
+	}
+	return s.countInBucket(db, ns, prefix)
+}
+
+// RangeNS calls fn with items having keys from "from" to "to" in a given bucket in order, until fn returns false
+func (s *NSStorage) RangeNS(ns, from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return storage.InternalError("couldn't load db")
+	}
+	return s.rangeInBucket(db, ns, from, to, fn)
 }
@@ -1,13 +1,20 @@
 package boltstorage
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/bloom"
+	"github.com/yowcow/goromdb/testutil"
 )
 
 var sampleNSDBFile = "../../_data/store/sample-ns-boltdb.db"
 
+var sampleNestedNSDBFile = "../../_data/store/sample-nested-ns-boltdb.db"
+
 func TestNewNS(t *testing.T) {
 	NewNS()
 }
@@ -66,3 +73,278 @@ func TestGetNS(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNSWithBloomFilter(t *testing.T) {
+	s := NewNS()
+	s.UseBloomFilter(0.01)
+	err := s.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		input         [2][]byte
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{
+			"namespace and key exists",
+			[2][]byte{[]byte("ns1"), []byte("hoge")},
+			[]byte("hoge1"),
+			false,
+		},
+		{
+			"namespace exists but key not exists",
+			[2][]byte{[]byte("ns1"), []byte("fuga")},
+			nil,
+			true,
+		},
+		{
+			"namespace not exists",
+			[2][]byte{[]byte("ns3"), []byte("hoge")},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedVal, v)
+			assert.True(t, c.errorExpected == (err != nil))
+		})
+	}
+}
+
+func TestGetNSWithBloomFilterSidecar(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db")
+	testutil.CopyFile(file, sampleNSDBFile)
+
+	filter := bloom.New(1, 0.0001)
+	filter.Add(bloom.NSKey([]byte("ns2"), []byte("hoge")))
+	sum, _ := bloom.FileSum(file)
+	filter.SetScope(bloom.Scope{Sum: sum, Bucket: ""})
+	filter.WriteFile(file + bloom.Suffix)
+
+	s := NewNS()
+	s.UseBloomFilter(0.01)
+	err := s.Load(file)
+
+	assert.Nil(t, err)
+
+	v, err := s.GetNS([]byte("ns2"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge2"), v)
+
+	v, err = s.GetNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestNamespacesAndKeysNS(t *testing.T) {
+	s := NewNS()
+	err := s.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	names, err := s.Namespaces()
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("ns1"), []byte("ns2")}, names)
+
+	keys, err := s.KeysNS([]byte("ns1"), []byte("ho"), nil, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("hoge")}, keys)
+
+	n, err := s.CountNS([]byte("ns2"), nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = s.KeysNS([]byte("ns3"), nil, nil, 0)
+
+	assert.NotNil(t, err)
+
+	_, err = s.CountNS([]byte("ns3"), nil)
+
+	assert.NotNil(t, err)
+}
+
+func TestRangeNS(t *testing.T) {
+	s := NewNS()
+	s.Load(sampleNSDBFile)
+
+	var actual []string
+	err := s.RangeNS([]byte("ns2"), []byte("a"), []byte("z"), func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hoge=hoge2"}, actual)
+
+	err = s.RangeNS([]byte("ns3"), nil, nil, func(key []byte, item *storage.Item) bool {
+		return true
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestGetNSWithBucketPath(t *testing.T) {
+	s := NewNS()
+	s.UseBucketPath("/")
+	err := s.Load(sampleNestedNSDBFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		input         [2][]byte
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{"top level bucket", [2][]byte{[]byte("tenant1"), []byte("hoge")}, []byte("hoge0"), false},
+		{"nested bucket", [2][]byte{[]byte("tenant1/dataset1"), []byte("hoge")}, []byte("hoge1"), false},
+		{"deeply nested bucket", [2][]byte{[]byte("tenant1/dataset2/archive"), []byte("hoge")}, []byte("hoge3"), false},
+		{"nested bucket as key", [2][]byte{[]byte("tenant1"), []byte("dataset1")}, nil, true},
+		{"non-existing nested bucket", [2][]byte{[]byte("tenant2/dataset2"), []byte("hoge")}, nil, true},
+		{"non-existing parent bucket", [2][]byte{[]byte("tenant3/dataset1"), []byte("hoge")}, nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.errorExpected, err != nil)
+			assert.Equal(t, c.expectedVal, v)
+		})
+	}
+}
+
+func TestGetNSWithBucketPathAndBloomFilter(t *testing.T) {
+	s := NewNS()
+	s.UseBucketPath(":")
+	s.UseBloomFilter(0.0001)
+	err := s.Load(sampleNestedNSDBFile)
+
+	assert.Nil(t, err)
+
+	v, err := s.GetNS([]byte("tenant1:dataset2:archive"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge3"), v)
+
+	v, err = s.GetNS([]byte("tenant1/dataset2"), []byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestGetNSWithBucketPathAndBloomFilterSidecar(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db")
+	testutil.CopyFile(file, sampleNestedNSDBFile)
+	sum, _ := bloom.FileSum(file)
+
+	type Case struct {
+		separator     string
+		errorExpected bool
+		subtest       string
+	}
+	cases := []Case{
+		{"/", true, "filter of the same separator is used"},
+		{":", false, "filter of another separator is ignored"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			filter := bloom.New(1, 0.0001)
+			filter.Add(bloom.NSKey([]byte("tenant1"), []byte("hoge")))
+			filter.SetScope(bloom.Scope{Sum: sum, Bucket: "", Separator: c.separator})
+			filter.WriteFile(file + bloom.Suffix)
+
+			s := NewNS()
+			s.UseBucketPath("/")
+			s.UseBloomFilter(0.0001)
+			err := s.Load(file)
+
+			assert.Nil(t, err)
+
+			_, err = s.GetNS([]byte("tenant1/dataset1"), []byte("hoge"))
+
+			assert.Equal(t, c.errorExpected, err != nil)
+		})
+	}
+}
+
+func TestLoadWithBucketPathFailsByBucketNameHavingSeparator(t *testing.T) {
+	s := NewNS()
+	s.UseBucketPath("s")
+	err := s.Load(sampleNSDBFile)
+
+	assert.NotNil(t, err)
+	assert.Nil(t, s.getDB())
+}
+
+func TestNamespacesAndKeysNSWithBucketPath(t *testing.T) {
+	s := NewNS()
+	s.UseBucketPath("/")
+	s.Load(sampleNestedNSDBFile)
+
+	names, err := s.Namespaces()
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{
+		[]byte("tenant1"),
+		[]byte("tenant1/dataset1"),
+		[]byte("tenant1/dataset2"),
+		[]byte("tenant1/dataset2/archive"),
+		[]byte("tenant2"),
+		[]byte("tenant2/dataset1"),
+	}, names)
+
+	keys, err := s.KeysNS([]byte("tenant1"), nil, nil, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("hoge")}, keys)
+
+	n, err := s.CountNS([]byte("tenant1/dataset2"), nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	var actual []string
+	err = s.RangeNS([]byte("tenant2/dataset1"), nil, nil, func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fuga=fuga1"}, actual)
+}
+
+func TestGetNSWithoutBucketPath(t *testing.T) {
+	s := NewNS()
+	s.Load(sampleNestedNSDBFile)
+
+	v, err := s.GetNS([]byte("tenant1/dataset1"), []byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+
+	names, err := s.Namespaces()
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("tenant1"), []byte("tenant2")}, names)
+}
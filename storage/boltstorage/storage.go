@@ -1,27 +1,53 @@
 package boltstorage
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
 	"sync"
 	"sync/atomic"
 
 	"github.com/boltdb/bolt"
 	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/bloom"
 )
 
 var (
-	_ storage.Storage = (*Storage)(nil)
+	_ storage.Storage       = (*Storage)(nil)
+	_ storage.Iterable      = (*Storage)(nil)
+	_ storage.RangeIterable = (*Storage)(nil)
+	_ storage.Generational  = (*Storage)(nil)
+	_ io.Closer             = (*Storage)(nil)
 )
 
 // Storage represents a BoltDB storage
 type Storage struct {
-	db     *atomic.Value
-	bucket []byte
-	mux    *sync.RWMutex
+	db          *atomic.Value
+	bucket      []byte
+	mux         *sync.RWMutex
+	bloomFPRate float64
+	separator   []byte
+	generation  *uint64
+}
+
+// handle represents a db handle and a bloom filter of keys in the db, to be swapped together
+type handle struct {
+	db     *bolt.DB
+	filter *bloom.Filter
 }
 
 // New creates and returns a storage
 func New(b string) *Storage {
-	return &Storage{new(atomic.Value), []byte(b), new(sync.RWMutex)}
+	return &Storage{new(atomic.Value), []byte(b), new(sync.RWMutex), 0, nil, new(uint64)}
+}
+
+// UseBloomFilter makes storage look up a bloom filter before db, to find non-existing keys without touching db.
+// The filter is read from a sidecar file with bloom.Suffix if exists and built for the same db file and bucket,
+// or otherwise built from db keys at load.
+// This must be called before loading any data.
+func (s *Storage) UseBloomFilter(fpRate float64) {
+	s.bloomFPRate = fpRate
 }
 
 // Load loads a new db handle into storage, and closes old db handle if exists
@@ -31,20 +57,192 @@ func (s *Storage) Load(file string) error {
 		return err
 	}
 
+	if err := s.checkBucketNames(newDB); err != nil {
+		newDB.Close()
+		return err
+	}
+
+	filter, err := s.loadFilter(newDB, file)
+	if err != nil {
+		newDB.Close()
+		return err
+	}
+
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
 	oldDB := s.getDB()
-	s.db.Store(newDB)
+	s.db.Store(&handle{newDB, filter})
+	atomic.AddUint64(s.generation, 1)
 	if oldDB != nil {
 		oldDB.Close()
 	}
 	return nil
 }
 
-func (s *Storage) getDB() *bolt.DB {
+// Close waits for reads in progress, and closes db handle if exists
+func (s *Storage) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldDB := s.getDB()
+	if oldDB == nil {
+		return nil
+	}
+	s.db.Store((*handle)(nil))
+	return oldDB.Close()
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+func (s *Storage) loadFilter(db *bolt.DB, file string) (*bloom.Filter, error) {
+	if s.bloomFPRate <= 0 {
+		return nil, nil
+	}
+	if filter := readFilter(file, s.bucket, s.separator); filter != nil {
+		return filter, nil
+	}
+	if s.bucket == nil {
+		return s.buildNSFilter(db)
+	}
+	return buildFilter(db, s.bucket, s.bloomFPRate)
+}
+
+// readFilter returns a filter in a sidecar file if exists and built for file, bucket and separator, or otherwise nil to build a new one
+func readFilter(file string, bucket, separator []byte) *bloom.Filter {
+	if _, err := os.Stat(file + bloom.Suffix); err != nil {
+		return nil
+	}
+	filter, err := bloom.ReadFile(file + bloom.Suffix)
+	if err != nil {
+		return nil
+	}
+	sum, err := bloom.FileSum(file)
+	if err != nil || filter.Scope() != (bloom.Scope{Sum: sum, Bucket: string(bucket), Separator: string(separator)}) {
+		return nil
+	}
+	return filter
+}
+
+func buildFilter(db *bolt.DB, bucket []byte, fpRate float64) (*bloom.Filter, error) {
+	var filter *bloom.Filter
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			filter = bloom.New(0, fpRate)
+			return nil
+		}
+		filter = bloom.New(b.Stats().KeyN, fpRate)
+		return b.ForEach(func(k, v []byte) error {
+			filter.Add(k)
+			return nil
+		})
+	})
+	return filter, err
+}
+
+func (s *Storage) buildNSFilter(db *bolt.DB) (*bloom.Filter, error) {
+	var filter *bloom.Filter
+	err := db.View(func(tx *bolt.Tx) error {
+		n := 0
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			n += b.Stats().KeyN
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		filter = bloom.New(n, s.bloomFPRate)
+		return s.forEachNS(tx, func(ns []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, v []byte) error {
+				if v != nil || len(s.separator) == 0 {
+					filter.Add(bloom.NSKey(ns, k))
+				}
+				return nil
+			})
+		})
+	})
+	return filter, err
+}
+
+// forEachNS calls fn with each bucket and its namespace.
+// Nested buckets are namespaces joined by separator if separator is set, or otherwise only top level buckets are.
+func (s *Storage) forEachNS(tx *bolt.Tx, fn func(ns []byte, b *bolt.Bucket) error) error {
+	return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		return s.walkBucket(name, b, fn)
+	})
+}
+
+func (s *Storage) walkBucket(ns []byte, b *bolt.Bucket, fn func([]byte, *bolt.Bucket) error) error {
+	if err := fn(ns, b); err != nil {
+		return err
+	}
+	if len(s.separator) == 0 {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		child := make([]byte, 0, len(ns)+len(s.separator)+len(k))
+		child = append(append(append(child, ns...), s.separator...), k...)
+		return s.walkBucket(child, b.Bucket(k), fn)
+	})
+}
+
+// checkBucketNames fails if separator is set and a bucket has a name containing it, which is unreachable as a path of nested buckets
+func (s *Storage) checkBucketNames(db *bolt.DB) error {
+	if len(s.separator) == 0 {
+		return nil
+	}
+	return db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return s.checkBucketName(name, b)
+		})
+	})
+}
+
+func (s *Storage) checkBucketName(name []byte, b *bolt.Bucket) error {
+	if bytes.Contains(name, s.separator) {
+		return storage.InternalError(fmt.Sprintf("bucket name '%s' contains separator '%s'", name, s.separator))
+	}
+	return b.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return s.checkBucketName(k, b.Bucket(k))
+	})
+}
+
+// lookupBucket finds a bucket by name, which is a path of nested buckets if separator is set
+func (s *Storage) lookupBucket(tx *bolt.Tx, name []byte) *bolt.Bucket {
+	if len(s.separator) == 0 {
+		return tx.Bucket(name)
+	}
+	path := bytes.Split(name, s.separator)
+	b := tx.Bucket(path[0])
+	for _, p := range path[1:] {
+		if b == nil {
+			return nil
+		}
+		b = b.Bucket(p)
+	}
+	return b
+}
+
+func (s *Storage) getHandle() *handle {
 	if ptr := s.db.Load(); ptr != nil {
-		return ptr.(*bolt.DB)
+		return ptr.(*handle)
+	}
+	return nil
+}
+
+func (s *Storage) getDB() *bolt.DB {
+	if h := s.getHandle(); h != nil {
+		return h.db
 	}
 	return nil
 }
@@ -58,19 +256,22 @@ func (s *Storage) Get(key []byte) ([]byte, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
-	db := s.getDB()
-	if db == nil {
+	h := s.getHandle()
+	if h == nil {
 		return nil, storage.InternalError("couldn't load db")
 	}
+	if h.filter != nil && s.bucket != nil && !h.filter.Test(key) {
+		return nil, storage.KeyNotFoundError(key)
+	}
 
-	return getFromBucket(db, s.bucket, key)
+	return s.getFromBucket(h.db, s.bucket, key)
 }
 
-func getFromBucket(db *bolt.DB, bucket, key []byte) ([]byte, error) {
+func (s *Storage) getFromBucket(db *bolt.DB, bucket, key []byte) ([]byte, error) {
 	var retVal []byte
 
 	err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(bucket)
+		b := s.lookupBucket(tx, bucket)
 		if b == nil {
 			return storage.BucketNotFoundError(bucket)
 		}
@@ -93,3 +294,116 @@ func getFromBucket(db *bolt.DB, bucket, key []byte) ([]byte, error) {
 
 	return retVal, nil
 }
+
+// Keys finds keys having prefix in bucket, and returns up to limit keys starting at start
+func (s *Storage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+	return s.keysInBucket(db, s.bucket, prefix, start, limit)
+}
+
+// Count counts keys having prefix in bucket
+func (s *Storage) Count(prefix []byte) (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return 0, storage.InternalError("couldn't load db")
+	}
+	return s.countInBucket(db, s.bucket, prefix)
+}
+
+// Range calls fn with items having keys from "from" to "to" in bucket in order, until fn returns false
+func (s *Storage) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return storage.InternalError("couldn't load db")
+	}
+	return s.rangeInBucket(db, s.bucket, from, to, fn)
+}
+
+func (s *Storage) rangeInBucket(db *bolt.DB, bucket, from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return db.View(func(tx *bolt.Tx) error {
+		b := s.lookupBucket(tx, bucket)
+		if b == nil {
+			return storage.BucketNotFoundError(bucket)
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(from); k != nil; k, v = c.Next() {
+			if len(to) > 0 && bytes.Compare(k, to) > 0 {
+				break
+			}
+			if v == nil {
+				// A nested bucket has no value
+				continue
+			}
+			// Copying, since key and value are valid only in transaction
+			item := &storage.Item{Value: append([]byte(nil), v...)}
+			if !fn(append([]byte(nil), k...), item) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) keysInBucket(db *bolt.DB, bucket, prefix, start []byte, limit int) ([][]byte, error) {
+	var keys [][]byte
+	err := db.View(func(tx *bolt.Tx) error {
+		b := s.lookupBucket(tx, bucket)
+		if b == nil {
+			return storage.BucketNotFoundError(bucket)
+		}
+		seek := prefix
+		if bytes.Compare(start, prefix) > 0 {
+			seek = start
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(seek); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if limit > 0 && len(keys) >= limit {
+				break
+			}
+			if v == nil {
+				// A nested bucket has no value
+				continue
+			}
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *Storage) countInBucket(db *bolt.DB, bucket, prefix []byte) (int, error) {
+	n := 0
+	err := db.View(func(tx *bolt.Tx) error {
+		b := s.lookupBucket(tx, bucket)
+		if b == nil {
+			return storage.BucketNotFoundError(bucket)
+		}
+		if len(prefix) == 0 && len(s.separator) == 0 {
+			n = b.Stats().KeyN
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if v != nil {
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
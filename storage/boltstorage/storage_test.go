@@ -1,9 +1,14 @@
 package boltstorage
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/bloom"
+	"github.com/yowcow/goromdb/testutil"
 )
 
 var sampleDBFile = "../../_data/store/sample-boltdb.db"
@@ -51,6 +56,26 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestClose(t *testing.T) {
+	s := New("goromdb")
+
+	assert.Nil(t, s.Close())
+
+	s.Load(sampleDBFile)
+
+	assert.Nil(t, s.Close())
+
+	_, err := s.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+	assert.Nil(t, s.Load(sampleDBFile))
+
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.NotNil(t, v)
+}
+
 func TestGet(t *testing.T) {
 	s := New("goromdb")
 	v, err := s.Get([]byte("hoge"))
@@ -89,3 +114,188 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestGetWithBloomFilter(t *testing.T) {
+	s := New("goromdb")
+	s.UseBloomFilter(0.01)
+	err := s.Load(sampleDBFile)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, s.getHandle().filter)
+
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge!"), v)
+
+	v, err = s.Get([]byte("hogehoge"))
+
+	assert.Nil(t, v)
+	assert.True(t, storage.IsErrorKeyNotFound(err))
+}
+
+func TestGetWithBloomFilterSidecar(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db")
+	testutil.CopyFile(file, sampleDBFile)
+
+	// a filter without "hoge" proves that filter is looked up before db
+	filter := bloom.New(1, 0.0001)
+	filter.Add([]byte("fuga"))
+	sum, _ := bloom.FileSum(file)
+	filter.SetScope(bloom.Scope{Sum: sum, Bucket: "goromdb"})
+	filter.WriteFile(file + bloom.Suffix)
+
+	s := New("goromdb")
+	s.UseBloomFilter(0.01)
+	err := s.Load(file)
+
+	assert.Nil(t, err)
+
+	v, err := s.Get([]byte("fuga"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fuga!!"), v)
+
+	v, err = s.Get([]byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.True(t, storage.IsErrorKeyNotFound(err))
+}
+
+func TestGetWithBloomFilterSidecarOfAnotherDB(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.db")
+	testutil.CopyFile(file, sampleDBFile)
+	sum, _ := bloom.FileSum(file)
+
+	type Case struct {
+		scope   bloom.Scope
+		subtest string
+	}
+	cases := []Case{
+		{bloom.Scope{Sum: sum + 1, Bucket: "goromdb"}, "filter of another db file is ignored"},
+		{bloom.Scope{Sum: sum, Bucket: "hoge"}, "filter of another bucket is ignored"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			filter := bloom.New(1, 0.0001)
+			filter.Add([]byte("fuga"))
+			filter.SetScope(c.scope)
+			filter.WriteFile(file + bloom.Suffix)
+
+			s := New("goromdb")
+			s.UseBloomFilter(0.01)
+			err := s.Load(file)
+
+			assert.Nil(t, err)
+
+			v, err := s.Get([]byte("hoge"))
+
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("hoge!"), v)
+		})
+	}
+}
+
+func TestKeysAndCount(t *testing.T) {
+	s := New("goromdb")
+	_, err := s.Keys(nil, nil, 0)
+
+	assert.NotNil(t, err)
+
+	s.Load(sampleDBFile)
+
+	type Case struct {
+		prefix        []byte
+		start         []byte
+		limit         int
+		expectedKeys  [][]byte
+		expectedCount int
+		subtest       string
+	}
+	cases := []Case{
+		{
+			nil, nil, 0,
+			[][]byte{[]byte("bar"), []byte("buz"), []byte("foo"), []byte("fuga"), []byte("hoge")},
+			5,
+			"all keys in order",
+		},
+		{
+			[]byte("f"), nil, 0,
+			[][]byte{[]byte("foo"), []byte("fuga")},
+			2,
+			"keys having prefix",
+		},
+		{
+			[]byte("f"), []byte("fp"), 0,
+			[][]byte{[]byte("fuga")},
+			2,
+			"keys having prefix starting at start",
+		},
+		{
+			nil, []byte("buz"), 2,
+			[][]byte{[]byte("buz"), []byte("foo")},
+			5,
+			"keys up to limit starting at start",
+		},
+		{
+			[]byte("x"), nil, 0,
+			nil,
+			0,
+			"no key having prefix",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			keys, err := s.Keys(c.prefix, c.start, c.limit)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedKeys, keys)
+
+			n, err := s.Count(c.prefix)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedCount, n)
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	s := New("goromdb")
+	s.Load(sampleDBFile)
+
+	type Case struct {
+		from     []byte
+		to       []byte
+		stopAt   int
+		expected []string
+		subtest  string
+	}
+	cases := []Case{
+		{[]byte("buz"), []byte("fuga"), 0, []string{"buz=buz!!!!!", "foo=foo!!!", "fuga=fuga!!"}, "keys from and to inclusive"},
+		{[]byte("c"), []byte("fp"), 0, []string{"foo=foo!!!"}, "keys between non-existing keys"},
+		{[]byte("fuga"), nil, 0, []string{"fuga=fuga!!", "hoge=hoge!"}, "keys without upper bound"},
+		{nil, nil, 2, []string{"bar=bar!!!!", "buz=buz!!!!!"}, "keys until fn returns false"},
+		{[]byte("x"), nil, 0, nil, "no key in range"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			var actual []string
+			err := s.Range(c.from, c.to, func(key []byte, item *storage.Item) bool {
+				actual = append(actual, string(key)+"="+string(item.Value))
+				return len(actual) != c.stopAt
+			})
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
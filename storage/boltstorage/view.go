@@ -0,0 +1,53 @@
+package boltstorage
+
+import (
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.Storage       = (*View)(nil)
+	_ storage.Iterable      = (*View)(nil)
+	_ storage.RangeIterable = (*View)(nil)
+	_ storage.Generational  = (*View)(nil)
+)
+
+// View represents a storage scoped to a bucket in a namespaced storage, sharing its db handle with other views
+type View struct {
+	shared *NSStorage
+	bucket []byte
+}
+
+// View creates and returns a storage scoped to bucket, so that a file of many buckets is opened once for all of them
+func (s *NSStorage) View(bucket string) *View {
+	return &View{s, []byte(bucket)}
+}
+
+// Load loads a new db handle into the shared storage, which swaps db handles of all views at once
+func (v *View) Load(file string) error {
+	return v.shared.Load(file)
+}
+
+// Generation returns the generation of the shared storage, which is the same among views
+func (v *View) Generation() uint64 {
+	return v.shared.Generation()
+}
+
+// Get finds a given key in bucket, and returns its value
+func (v *View) Get(key []byte) ([]byte, error) {
+	return v.shared.GetNS(v.bucket, key)
+}
+
+// Keys finds keys having prefix in bucket, and returns up to limit keys starting at start
+func (v *View) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return v.shared.KeysNS(v.bucket, prefix, start, limit)
+}
+
+// Count counts keys having prefix in bucket
+func (v *View) Count(prefix []byte) (int, error) {
+	return v.shared.CountNS(v.bucket, prefix)
+}
+
+// Range calls fn with items having keys from "from" to "to" in bucket in order, until fn returns false
+func (v *View) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return v.shared.RangeNS(v.bucket, from, to, fn)
+}
@@ -0,0 +1,102 @@
+package boltstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
+)
+
+func TestViewGet(t *testing.T) {
+	s := NewNS()
+	ns1 := s.View("ns1")
+	ns2 := s.View("ns2")
+	err := ns1.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		view          *View
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{"first view finds its value", ns1, []byte("hoge1"), false},
+		{"second view finds its value", ns2, []byte("hoge2"), false},
+		{"non-existing bucket fails", s.View("ns3"), nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := c.view.Get([]byte("hoge"))
+
+			assert.Equal(t, c.errorExpected, err != nil)
+			assert.Equal(t, c.expectedVal, v)
+		})
+	}
+}
+
+func TestViewLoadSwapsAllViews(t *testing.T) {
+	s := NewNS()
+	s.UseBucketPath("/")
+	ns1 := s.View("ns1")
+	tenant1 := s.View("tenant1/dataset1")
+
+	assert.Nil(t, ns1.Load(sampleNSDBFile))
+
+	_, err := tenant1.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+
+	assert.Nil(t, tenant1.Load(sampleNestedNSDBFile))
+
+	v, err := tenant1.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge1"), v)
+
+	_, err = ns1.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+}
+
+func TestViewGeneration(t *testing.T) {
+	s := NewNS()
+	ns1 := s.View("ns1")
+	ns2 := s.View("ns2")
+
+	assert.Equal(t, uint64(0), ns2.Generation())
+	assert.Nil(t, ns1.Load(sampleNSDBFile))
+	assert.Equal(t, uint64(1), ns2.Generation())
+	assert.NotNil(t, ns1.Load(sampleNSDBFile+".hoge"))
+	assert.Equal(t, uint64(1), ns2.Generation())
+	assert.Nil(t, ns2.Load(sampleNSDBFile))
+	assert.Equal(t, uint64(2), ns1.Generation())
+	assert.Equal(t, uint64(2), s.Generation())
+}
+
+func TestViewKeysAndRange(t *testing.T) {
+	s := NewNS()
+	v := s.View("ns2")
+	v.Load(sampleNSDBFile)
+
+	keys, err := v.Keys(nil, nil, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("hoge")}, keys)
+
+	n, err := v.Count([]byte("ho"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	var actual []string
+	err = v.Range(nil, nil, func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hoge=hoge2"}, actual)
+}
@@ -0,0 +1,84 @@
+package cachestorage
+
+import (
+	"container/list"
+	"sync"
+)
+
+// entryOverhead approximates bytes taken by an entry besides its key and value
+const entryOverhead = 64
+
+type entry struct {
+	key  string
+	val  []byte
+	size int
+}
+
+// lru represents a size-bounded least-recently-used cache.
+// An entry with nil value represents a negative lookup.
+type lru struct {
+	mux       *sync.Mutex
+	maxBytes  int
+	bytes     int
+	evictions uint64
+	ll        *list.List
+	items     map[string]*list.Element
+}
+
+func newLRU(maxBytes int) *lru {
+	return &lru{
+		mux:      new(sync.Mutex),
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns cached value and whether or not the key is cached
+func (c *lru) get(key string) ([]byte, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*entry).val, true
+}
+
+// add caches given value, and evicts least-recently-used entries to stay within maxBytes
+func (c *lru) add(key string, val []byte) {
+	size := len(key) + len(val) + entryOverhead
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+	c.items[key] = c.ll.PushFront(&entry{key, val, size})
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		c.removeElement(c.ll.Back())
+		c.evictions++
+	}
+}
+
+func (c *lru) removeElement(elem *list.Element) {
+	e := c.ll.Remove(elem).(*entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
+
+// stats returns number of items, bytes and evictions
+func (c *lru) stats() (int, int, uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.ll.Len(), c.bytes, c.evictions
+}
@@ -0,0 +1,75 @@
+package cachestorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUGetAndAdd(t *testing.T) {
+	c := newLRU(1024)
+
+	_, ok := c.get("hoge")
+
+	assert.False(t, ok)
+
+	c.add("hoge", []byte("hoge!"))
+	c.add("fuga", nil)
+
+	val, ok := c.get("hoge")
+
+	assert.True(t, ok)
+	assert.Equal(t, []byte("hoge!"), val)
+
+	val, ok = c.get("fuga")
+
+	assert.True(t, ok)
+	assert.Nil(t, val)
+
+	items, bytes, _ := c.stats()
+
+	assert.Equal(t, 2, items)
+	assert.Equal(t, 4+5+4+2*entryOverhead, bytes)
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRU(3 * (entryOverhead + 2))
+
+	c.add("k1", nil)
+	c.add("k2", nil)
+	c.add("k3", nil)
+	c.get("k1")
+	c.add("k4", nil)
+
+	type Case struct {
+		key      string
+		expectOK bool
+	}
+	cases := []Case{
+		{"k1", true},
+		{"k2", false},
+		{"k3", true},
+		{"k4", true},
+	}
+
+	for _, c2 := range cases {
+		t.Run(c2.key, func(t *testing.T) {
+			_, ok := c.get(c2.key)
+			assert.Equal(t, c2.expectOK, ok)
+		})
+	}
+
+	items, _, evictions := c.stats()
+
+	assert.Equal(t, 3, items)
+	assert.Equal(t, uint64(1), evictions)
+}
+
+func TestLRUSkipsEntryLargerThanMaxBytes(t *testing.T) {
+	c := newLRU(entryOverhead)
+
+	c.add("hoge", []byte("hoge!"))
+	_, ok := c.get("hoge")
+
+	assert.False(t, ok)
+}
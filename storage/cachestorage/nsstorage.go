@@ -0,0 +1,71 @@
+package cachestorage
+
+import (
+	"encoding/binary"
+
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.NSStorage       = (*NSStorage)(nil)
+	_ storage.NSIterable      = (*NSStorage)(nil)
+	_ storage.NSRangeIterable = (*NSStorage)(nil)
+)
+
+// NSStorage represents a namespaced storage caching values from another namespaced storage in memory
+type NSStorage struct {
+	Storage
+	nsproxy storage.NSStorage
+}
+
+// NewNS creates and returns a namespaced storage caching up to maxBytes of values found in proxy
+func NewNS(proxy storage.NSStorage, maxBytes int, cacheMisses bool) *NSStorage {
+	return &NSStorage{*New(proxy, maxBytes, cacheMisses), proxy}
+}
+
+// Get finds a given key in cache or in proxy storage, and returns its value
+func (s *NSStorage) Get(key []byte) ([]byte, error) {
+	return s.fetch(nsCacheKey(nil, key), key, func() ([]byte, error) {
+		return s.nsproxy.Get(key)
+	})
+}
+
+// GetNS finds a given ns+key in cache or in proxy storage, and returns its value
+func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	return s.fetch(nsCacheKey(ns, key), key, func() ([]byte, error) {
+		return s.nsproxy.GetNS(ns, key)
+	})
+}
+
+// nsCacheKey builds a cache key unique to ns+key, which does not collide with a key without namespace
+func nsCacheKey(ns, key []byte) string {
+	if ns == nil {
+		return "\x00" + string(key)
+	}
+	buf := make([]byte, 1, 1+binary.MaxVarintLen64+len(ns)+len(key))
+	buf[0] = 1
+	buf = buf[:1+binary.PutUvarint(buf[1:cap(buf)], uint64(len(ns)))]
+	buf = append(buf, ns...)
+	buf = append(buf, key...)
+	return string(buf)
+}
+
+// Namespaces returns namespaces in proxy storage, bypassing cache, if it is iterable
+func (s *NSStorage) Namespaces() ([][]byte, error) {
+	return storage.Namespaces(s.nsproxy)
+}
+
+// KeysNS finds keys in given namespace in proxy storage, bypassing cache, if it is iterable
+func (s *NSStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.KeysNS(s.nsproxy, ns, prefix, start, limit)
+}
+
+// CountNS counts keys in given namespace in proxy storage, bypassing cache, if it is iterable
+func (s *NSStorage) CountNS(ns, prefix []byte) (int, error) {
+	return storage.CountNS(s.nsproxy, ns, prefix)
+}
+
+// RangeNS calls fn with items having keys in a range in namespace in proxy storage, bypassing cache, if it supports range
+func (s *NSStorage) RangeNS(ns, from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return storage.RangeNS(s.nsproxy, ns, from, to, fn)
+}
@@ -0,0 +1,76 @@
+package cachestorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+)
+
+var sampleNSDataFile = "../../_data/store/sample-ns-data.json"
+
+func TestNewNS(t *testing.T) {
+	p := jsonstorage.NewNS(false)
+	NewNS(p, 1024, false)
+}
+
+func TestGetNS(t *testing.T) {
+	p := jsonstorage.NewNS(false)
+	s := NewNS(p, 1024, true)
+	err := s.Load(sampleNSDataFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		input         [2][]byte
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{
+			"namespace and key exists",
+			[2][]byte{[]byte("ns1"), []byte("hoge")},
+			[]byte("hoge1"),
+			false,
+		},
+		{
+			"namespace and key exists 2",
+			[2][]byte{[]byte("ns2"), []byte("hoge")},
+			[]byte("hoge2"),
+			false,
+		},
+		{
+			"namespace exists but key not exists",
+			[2][]byte{[]byte("ns1"), []byte("fuga")},
+			nil,
+			true,
+		},
+		{
+			"namespace and key exists again",
+			[2][]byte{[]byte("ns1"), []byte("hoge")},
+			[]byte("hoge1"),
+			false,
+		},
+		{
+			"namespace and key not colliding with the others",
+			[2][]byte{[]byte("ns"), []byte("1hoge")},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedVal, v)
+			assert.True(t, c.errorExpected == (err != nil))
+		})
+	}
+
+	stats := s.Stats()
+
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+}
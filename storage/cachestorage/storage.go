@@ -0,0 +1,147 @@
+package cachestorage
+
+import (
+	"io"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.Storage        = (*Storage)(nil)
+	_ storage.Iterable       = (*Storage)(nil)
+	_ storage.RangeIterable  = (*Storage)(nil)
+	_ storage.Generational   = (*Storage)(nil)
+	_ storage.MemoryReporter = (*Storage)(nil)
+	_ storage.StatsReporter  = (*Storage)(nil)
+	_ io.Closer              = (*Storage)(nil)
+)
+
+// Stats represents cache statistics
+type Stats struct {
+	Hits      uint64 // lookups served from cache
+	Misses    uint64 // lookups passed to underlying storage
+	Items     int    // entries currently cached
+	Bytes     int    // approximate bytes currently cached
+	Evictions uint64 // entries evicted since last load
+}
+
+type counters struct {
+	hits   uint64
+	misses uint64
+}
+
+// Storage represents a storage caching values from another storage in memory
+type Storage struct {
+	proxy       storage.Storage
+	maxBytes    int
+	cacheMisses bool
+	cache       *atomic.Value
+	counters    *counters
+}
+
+// New creates and returns a storage caching up to maxBytes of values found in proxy.
+// When cacheMisses is true, keys not found in proxy are cached as well.
+func New(proxy storage.Storage, maxBytes int, cacheMisses bool) *Storage {
+	s := &Storage{proxy, maxBytes, cacheMisses, new(atomic.Value), new(counters)}
+	s.cache.Store(newLRU(maxBytes))
+	return s
+}
+
+// Load loads data into proxy storage, and replaces cache with an empty one
+func (s *Storage) Load(file string) error {
+	// Bypass cache while loading so that values from old data are never cached into new cache
+	s.cache.Store((*lru)(nil))
+	defer s.cache.Store(newLRU(s.maxBytes))
+
+	return s.proxy.Load(file)
+}
+
+// Generation returns the generation of proxy storage
+func (s *Storage) Generation() uint64 {
+	return storage.Generation(s.proxy)
+}
+
+// Close closes proxy storage, and drops cache
+func (s *Storage) Close() error {
+	s.cache.Store(newLRU(s.maxBytes))
+	return storage.Close(s.proxy)
+}
+
+// Get finds a given key in cache or in proxy storage, and returns its value
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	return s.fetch(string(key), key, func() ([]byte, error) {
+		return s.proxy.Get(key)
+	})
+}
+
+func (s *Storage) fetch(cacheKey string, key []byte, get func() ([]byte, error)) ([]byte, error) {
+	// Values from proxy are added to the cache taken before looking up proxy, which is discarded if reloaded meanwhile
+	cache := s.cache.Load().(*lru)
+	if cache == nil {
+		return get()
+	}
+
+	if val, ok := cache.get(cacheKey); ok {
+		atomic.AddUint64(&s.counters.hits, 1)
+		if val == nil {
+			return nil, storage.KeyNotFoundError(key)
+		}
+		return val, nil
+	}
+	atomic.AddUint64(&s.counters.misses, 1)
+
+	val, err := get()
+	if err == nil {
+		if val == nil {
+			val = []byte{}
+		}
+		cache.add(cacheKey, val)
+	} else if s.cacheMisses && storage.IsErrorKeyNotFound(err) {
+		cache.add(cacheKey, nil)
+	}
+	return val, err
+}
+
+// Stats returns cache statistics
+func (s *Storage) Stats() Stats {
+	stats := Stats{
+		Hits:   atomic.LoadUint64(&s.counters.hits),
+		Misses: atomic.LoadUint64(&s.counters.misses),
+	}
+	if cache := s.cache.Load().(*lru); cache != nil {
+		stats.Items, stats.Bytes, stats.Evictions = cache.stats()
+	}
+	return stats
+}
+
+// MemoryEstimate returns estimated bytes taken by proxy storage and cache
+func (s *Storage) MemoryEstimate() uint64 {
+	return storage.MemoryEstimate(s.proxy) + uint64(s.Stats().Bytes)
+}
+
+// ReportStats calls fn with statistics of proxy storage, followed by cache statistics
+func (s *Storage) ReportStats(fn func(string, uint64)) {
+	storage.ReportStats(s.proxy, fn)
+	stats := s.Stats()
+	fn("cache_hits", stats.Hits)
+	fn("cache_misses", stats.Misses)
+	fn("cache_items", uint64(stats.Items))
+	fn("cache_bytes", uint64(stats.Bytes))
+	fn("cache_evictions", stats.Evictions)
+}
+
+// Keys finds keys in proxy storage, bypassing cache, if it is iterable
+func (s *Storage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.Keys(s.proxy, prefix, start, limit)
+}
+
+// Count counts keys in proxy storage, bypassing cache, if it is iterable
+func (s *Storage) Count(prefix []byte) (int, error) {
+	return storage.Count(s.proxy, prefix)
+}
+
+// Range calls fn with items having keys in a range in proxy storage, bypassing cache, if it supports range
+func (s *Storage) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return storage.Range(s.proxy, from, to, fn)
+}
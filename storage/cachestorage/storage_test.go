@@ -0,0 +1,155 @@
+package cachestorage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+var sampleDataFile = "../../_data/store/sample-data.json"
+
+func TestNew(t *testing.T) {
+	p := jsonstorage.New(false)
+	New(p, 1024, false)
+}
+
+func TestGeneration(t *testing.T) {
+	p := jsonstorage.New(false)
+	s := New(p, 1024, false)
+
+	assert.Equal(t, uint64(0), s.Generation())
+	assert.Nil(t, s.Load(sampleDataFile))
+	assert.Equal(t, uint64(1), s.Generation())
+	assert.Nil(t, p.Load(sampleDataFile))
+	assert.Equal(t, uint64(2), s.Generation())
+}
+
+func TestGet(t *testing.T) {
+	p := jsonstorage.New(false)
+	s := New(p, 1024, true)
+	err := s.Load(sampleDataFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		input       []byte
+		expectedVal []byte
+		expectError bool
+		expected    Stats
+		subtest     string
+	}
+	cases := []Case{
+		{
+			[]byte("hoge"),
+			[]byte("hoge!"),
+			false,
+			Stats{Hits: 0, Misses: 1, Items: 1},
+			"existing key misses cache",
+		},
+		{
+			[]byte("hoge"),
+			[]byte("hoge!"),
+			false,
+			Stats{Hits: 1, Misses: 1, Items: 1},
+			"existing key again hits cache",
+		},
+		{
+			[]byte("hogefuga"),
+			nil,
+			true,
+			Stats{Hits: 1, Misses: 2, Items: 2},
+			"non-existing key misses cache",
+		},
+		{
+			[]byte("hogefuga"),
+			nil,
+			true,
+			Stats{Hits: 2, Misses: 2, Items: 2},
+			"non-existing key again hits cache",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.Get(c.input)
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expectedVal, v)
+
+			stats := s.Stats()
+
+			assert.Equal(t, c.expected.Hits, stats.Hits)
+			assert.Equal(t, c.expected.Misses, stats.Misses)
+			assert.Equal(t, c.expected.Items, stats.Items)
+		})
+	}
+}
+
+func TestGetWithoutCachingMisses(t *testing.T) {
+	p := jsonstorage.New(false)
+	s := New(p, 1024, false)
+	s.Load(sampleDataFile)
+
+	for i := 0; i < 2; i++ {
+		v, err := s.Get([]byte("hogefuga"))
+
+		assert.Nil(t, v)
+		assert.NotNil(t, err)
+	}
+
+	stats := s.Stats()
+
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 0, stats.Items)
+}
+
+func TestLoadInvalidatesCache(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file1 := filepath.Join(dir, "data1.json")
+	file2 := filepath.Join(dir, "data2.json")
+	testutil.CopyFile(file1, sampleDataFile)
+	writeFile(file2, `{"hoge":"new hoge!","new":"new!"}`)
+
+	p := jsonstorage.New(false)
+	s := New(p, 1024, true)
+	s.Load(file1)
+
+	v, _ := s.Get([]byte("hoge"))
+
+	assert.Equal(t, []byte("hoge!"), v)
+
+	_, err := s.Get([]byte("new"))
+
+	assert.NotNil(t, err)
+
+	err = s.Load(file2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, s.Stats().Items)
+
+	v, _ = s.Get([]byte("hoge"))
+
+	assert.Equal(t, []byte("new hoge!"), v)
+
+	v, _ = s.Get([]byte("new"))
+
+	assert.Equal(t, []byte("new!"), v)
+}
+
+func writeFile(file, content string) {
+	fo, err := os.Create(file)
+	if err != nil {
+		panic(err)
+	}
+	defer fo.Close()
+	if _, err := fo.WriteString(content); err != nil {
+		panic(err)
+	}
+}
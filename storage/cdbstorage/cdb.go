@@ -0,0 +1,203 @@
+package cdbstorage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// headerSize defines the size of a header holding 256 pairs of hash table position and number of slots
+const headerSize = 256 * 8
+
+type table struct {
+	pos    uint32
+	nslots uint32
+}
+
+// hash returns a CDB hash of given key
+func hash(key []byte) uint32 {
+	h := uint32(5381)
+	for _, c := range key {
+		h = ((h << 5) + h) ^ uint32(c)
+	}
+	return h
+}
+
+// Reader represents a reader of a CDB file
+type Reader struct {
+	file   *os.File
+	tables [256]table
+}
+
+// OpenReader opens a CDB file, and returns a Reader
+func OpenReader(file string) (*Reader, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(fi, header); err != nil {
+		fi.Close()
+		return nil, fmt.Errorf("failed reading cdb header: %s", err.Error())
+	}
+
+	r := &Reader{file: fi}
+	for i := range r.tables {
+		r.tables[i].pos = binary.LittleEndian.Uint32(header[i*8:])
+		r.tables[i].nslots = binary.LittleEndian.Uint32(header[i*8+4:])
+	}
+	return r, nil
+}
+
+// Get finds a given key, and returns its value, or nil if not found
+func (r *Reader) Get(key []byte) ([]byte, error) {
+	h := hash(key)
+	t := r.tables[h&0xff]
+	if t.nslots == 0 {
+		return nil, nil
+	}
+
+	slot := (h >> 8) % t.nslots
+	buf := make([]byte, 8)
+	for i := uint32(0); i < t.nslots; i++ {
+		if _, err := r.file.ReadAt(buf, int64(t.pos)+int64(slot)*8); err != nil {
+			return nil, fmt.Errorf("failed reading cdb hash table: %s", err.Error())
+		}
+		slotHash := binary.LittleEndian.Uint32(buf)
+		pos := binary.LittleEndian.Uint32(buf[4:])
+		if pos == 0 {
+			return nil, nil
+		}
+		if slotHash == h {
+			if val, err := r.readRecord(pos, key); val != nil || err != nil {
+				return val, err
+			}
+		}
+		slot = (slot + 1) % t.nslots
+	}
+	return nil, nil
+}
+
+// readRecord reads a record at pos, and returns its value if its key matches given key
+func (r *Reader) readRecord(pos uint32, key []byte) ([]byte, error) {
+	buf := make([]byte, 8+len(key))
+	if _, err := r.file.ReadAt(buf, int64(pos)); err != nil {
+		return nil, fmt.Errorf("failed reading cdb record: %s", err.Error())
+	}
+	klen := binary.LittleEndian.Uint32(buf)
+	dlen := binary.LittleEndian.Uint32(buf[4:])
+	if klen != uint32(len(key)) || !bytes.Equal(buf[8:], key) {
+		return nil, nil
+	}
+
+	val := make([]byte, dlen)
+	if _, err := r.file.ReadAt(val, int64(pos)+8+int64(klen)); err != nil {
+		return nil, fmt.Errorf("failed reading cdb record: %s", err.Error())
+	}
+	return val, nil
+}
+
+// Close closes file
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+type slot struct {
+	hash uint32
+	pos  uint32
+}
+
+// Writer represents a writer of a CDB file
+type Writer struct {
+	file    *os.File
+	w       *bufio.Writer
+	pos     uint64
+	entries [256][]slot
+}
+
+// CreateWriter creates a CDB file, and returns a Writer
+func CreateWriter(file string) (*Writer, error) {
+	fo, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fo.Seek(headerSize, io.SeekStart); err != nil {
+		fo.Close()
+		return nil, err
+	}
+	return &Writer{file: fo, w: bufio.NewWriter(fo), pos: headerSize}, nil
+}
+
+// Put writes a record of given key and value
+func (w *Writer) Put(key, val []byte) error {
+	pos := w.pos
+	if err := w.write(uint32(len(key)), uint32(len(val)), key, val); err != nil {
+		return err
+	}
+	h := hash(key)
+	w.entries[h&0xff] = append(w.entries[h&0xff], slot{h, uint32(pos)})
+	return nil
+}
+
+func (w *Writer) write(data ...interface{}) error {
+	for _, v := range data {
+		if err := binary.Write(w.w, binary.LittleEndian, v); err != nil {
+			return fmt.Errorf("failed writing cdb: %s", err.Error())
+		}
+		w.pos += uint64(binary.Size(v))
+	}
+	if w.pos > math.MaxUint32 {
+		return fmt.Errorf("failed writing cdb: exceeding 4GB")
+	}
+	return nil
+}
+
+// Close writes hash tables and header, and closes file
+func (w *Writer) Close() error {
+	if err := w.finish(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+func (w *Writer) finish() error {
+	var tables [256]table
+	for i, entries := range w.entries {
+		nslots := uint32(len(entries) * 2)
+		tables[i] = table{uint32(w.pos), nslots}
+		if nslots == 0 {
+			continue
+		}
+
+		slots := make([]slot, nslots)
+		for _, e := range entries {
+			n := (e.hash >> 8) % nslots
+			for slots[n].pos != 0 {
+				n = (n + 1) % nslots
+			}
+			slots[n] = e
+		}
+		for _, s := range slots {
+			if err := w.write(s.hash, s.pos); err != nil {
+				return err
+			}
+		}
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+
+	header := make([]byte, headerSize)
+	for i, t := range tables {
+		binary.LittleEndian.PutUint32(header[i*8:], t.pos)
+		binary.LittleEndian.PutUint32(header[i*8+4:], t.nslots)
+	}
+	_, err := w.file.WriteAt(header, 0)
+	return err
+}
@@ -0,0 +1,95 @@
+package cdbstorage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, uint32(5381), hash([]byte("")))
+	assert.Equal(t, uint32(177604), hash([]byte("a")))
+}
+
+func TestWriteAndRead(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.cdb")
+	w, err := CreateWriter(file)
+
+	assert.Nil(t, err)
+
+	for i := 0; i < 1000; i++ {
+		err = w.Put([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("val-%d", i)))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Put([]byte(""), []byte("empty key")))
+	assert.Nil(t, w.Put([]byte("empty value"), []byte("")))
+	assert.Nil(t, w.Close())
+
+	r, err := OpenReader(file)
+
+	assert.Nil(t, err)
+
+	defer r.Close()
+
+	for i := 0; i < 1000; i++ {
+		v, err := r.Get([]byte(fmt.Sprintf("key-%d", i)))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("val-%d", i)), v)
+	}
+
+	type Case struct {
+		input    []byte
+		expected []byte
+		subtest  string
+	}
+	cases := []Case{
+		{[]byte(""), []byte("empty key"), "empty key is found"},
+		{[]byte("empty value"), []byte(""), "empty value is found"},
+		{[]byte("key-1000"), nil, "non-existing key returns nil"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := r.Get(c.input)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, v)
+		})
+	}
+}
+
+func TestReadEmptyDB(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data.cdb")
+	w, err := CreateWriter(file)
+
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	r, err := OpenReader(file)
+
+	assert.Nil(t, err)
+
+	defer r.Close()
+
+	v, err := r.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+}
+
+func TestOpenReaderWithInvalidFile(t *testing.T) {
+	r, err := OpenReader("../../_data/sample-data.json")
+
+	assert.Nil(t, r)
+	assert.NotNil(t, err)
+}
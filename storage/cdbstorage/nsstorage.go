@@ -0,0 +1,31 @@
+package cdbstorage
+
+import (
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/nskey"
+)
+
+var (
+	_ storage.NSStorage = (*NSStorage)(nil)
+)
+
+// NSStorage represents a namespaced CDB storage, having namespaces and keys encoded into single keys
+type NSStorage struct {
+	Storage
+	enc nskey.Encoding
+}
+
+// NewNS creates and returns a storage with given encoding of namespace and key.
+// nskey.Concat reads data by older versions, which concatenated namespace and key.
+func NewNS(enc nskey.Encoding) *NSStorage {
+	return &NSStorage{*New(), enc}
+}
+
+// GetNS finds a given ns+key in db, and returns its value
+func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	fullKey, err := s.enc.Encode(ns, key)
+	if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+	return s.Get(fullKey)
+}
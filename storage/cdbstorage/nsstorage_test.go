@@ -0,0 +1,86 @@
+package cdbstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage/nskey"
+)
+
+var sampleNSDBFile = "../../_data/store/sample-ns-cdb.db"
+
+func TestNewNS(t *testing.T) {
+	NewNS(nskey.LengthPrefix)
+}
+
+func TestGetNS(t *testing.T) {
+	s := NewNS(nskey.Concat)
+	err := s.Load(sampleDBFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		input         [2][]byte
+		expectedValue []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{
+			"namespace and key exists",
+			[2][]byte{[]byte("ho"), []byte("ge")},
+			[]byte("hoge!"),
+			false,
+		},
+		{
+			"namespace exists but key not exists",
+			[2][]byte{[]byte("ho"), []byte("ho")},
+			nil,
+			true,
+		},
+		{
+			"namespace and key not exist",
+			[2][]byte{[]byte("foo"), []byte("bar")},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedValue, v)
+			assert.True(t, c.errorExpected == (err != nil))
+		})
+	}
+}
+
+func TestGetNSWithEncoding(t *testing.T) {
+	type Case struct {
+		enc           nskey.Encoding
+		input         [2][]byte
+		expectedValue []byte
+		errorExpected bool
+		subtest       string
+	}
+	cases := []Case{
+		{nskey.LengthPrefix, [2][]byte{[]byte("ns1"), []byte("hoge")}, []byte("hoge1"), false, "namespace and key exists"},
+		{nskey.LengthPrefix, [2][]byte{[]byte("ns"), []byte("1hoge")}, nil, true, "namespace and key sharing concatenation never collide"},
+		{nskey.Separator(":"), [2][]byte{[]byte("ns:1"), []byte("hoge")}, nil, true, "namespace containing separator fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := NewNS(c.enc)
+			err := s.Load(sampleNSDBFile)
+
+			assert.Nil(t, err)
+
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedValue, v)
+			assert.Equal(t, c.errorExpected, err != nil)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package cdbstorage
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.Storage      = (*Storage)(nil)
+	_ storage.Generational = (*Storage)(nil)
+	_ io.Closer            = (*Storage)(nil)
+)
+
+// Storage represents a CDB storage
+type Storage struct {
+	db         *atomic.Value
+	mux        *sync.RWMutex
+	generation *uint64
+}
+
+// New creates and returns a storage
+func New() *Storage {
+	return &Storage{new(atomic.Value), new(sync.RWMutex), new(uint64)}
+}
+
+// Load loads a new db handle into storage, and closes old db handle if exists
+func (s *Storage) Load(file string) error {
+	newDB, err := OpenReader(file)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldDB := s.getDB()
+	s.db.Store(newDB)
+	atomic.AddUint64(s.generation, 1)
+	if oldDB != nil {
+		oldDB.Close()
+	}
+	return nil
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+// Close waits for reads in progress, and closes db handle if exists
+func (s *Storage) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldDB := s.getDB()
+	if oldDB == nil {
+		return nil
+	}
+	s.db.Store((*Reader)(nil))
+	return oldDB.Close()
+}
+
+func (s *Storage) getDB() *Reader {
+	if ptr := s.db.Load(); ptr != nil {
+		return ptr.(*Reader)
+	}
+	return nil
+}
+
+// Get finds a given key in db, and returns its value
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+
+	v, err := db.Get(key)
+	if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+	if v == nil {
+		return nil, storage.KeyNotFoundError(key)
+	}
+
+	return v, nil
+}
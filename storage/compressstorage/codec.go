@@ -0,0 +1,135 @@
+package compressstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// maxDecompressedSize limits bytes a value decompresses into, so that a small broken or hostile value never takes all memory
+const maxDecompressedSize = 64 << 20
+
+// Codec represents a compression codec of values
+type Codec byte
+
+const (
+	// None leaves values as they are
+	None Codec = iota
+	// Gzip decompresses values in gzip format
+	Gzip
+	// Zstd decompresses values in zstd format
+	Zstd
+	// Snappy decompresses values in snappy block format
+	Snappy
+	// LZ4 decompresses values in LZ4 frame format
+	LZ4
+	// Header takes the first byte of each value as the codec of the rest of the value
+	Header Codec = 0xff
+)
+
+var codecNames = map[Codec]string{
+	None:   "none",
+	Gzip:   "gzip",
+	Zstd:   "zstd",
+	Snappy: "snappy",
+	LZ4:    "lz4",
+	Header: "header",
+}
+
+func (c Codec) String() string {
+	if name, ok := codecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("codec(%d)", byte(c))
+}
+
+// ParseCodec returns a codec with given name
+func ParseCodec(name string) (Codec, error) {
+	for c, n := range codecNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return None, fmt.Errorf("unknown codec '%s'", name)
+}
+
+var (
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	zstdDecoderOnce sync.Once
+)
+
+// getZstdDecoder returns a decoder shared by all storages, which is safe for concurrent DecodeAll calls
+func getZstdDecoder() (*zstd.Decoder, error) {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil,
+			zstd.WithDecoderConcurrency(0),
+			zstd.WithDecoderMaxMemory(maxDecompressedSize),
+		)
+	})
+	return zstdDecoder, zstdDecoderErr
+}
+
+// Decompress decompresses given value with given codec
+func Decompress(codec Codec, val []byte) ([]byte, error) {
+	switch codec {
+	case None:
+		return val, nil
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(val))
+		if err != nil {
+			return nil, err
+		}
+		return readAll(r)
+	case Zstd:
+		d, err := getZstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return d.DecodeAll(val, nil)
+	case Snappy:
+		n, err := snappy.DecodedLen(val)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxDecompressedSize {
+			return nil, tooLargeError()
+		}
+		return snappy.Decode(nil, val)
+	case LZ4:
+		return readAll(lz4.NewReader(bytes.NewReader(val)))
+	case Header:
+		if len(val) == 0 {
+			return nil, fmt.Errorf("missing codec header")
+		}
+		if Codec(val[0]) == Header {
+			return nil, fmt.Errorf("invalid codec header: %d", val[0])
+		}
+		return Decompress(Codec(val[0]), val[1:])
+	default:
+		return nil, fmt.Errorf("unknown codec: %d", byte(codec))
+	}
+}
+
+// readAll reads all decompressed bytes from r, and fails if they exceed maxDecompressedSize
+func readAll(r io.Reader) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxDecompressedSize {
+		return nil, tooLargeError()
+	}
+	return b, nil
+}
+
+func tooLargeError() error {
+	return fmt.Errorf("value decompresses into more than %d bytes", maxDecompressedSize)
+}
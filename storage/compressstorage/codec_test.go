@@ -0,0 +1,136 @@
+package compressstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func compressGzip(val []byte) []byte {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	w.Write(val)
+	w.Close()
+	return buf.Bytes()
+}
+
+func compressZstd(val []byte) []byte {
+	e, _ := zstd.NewWriter(nil)
+	defer e.Close()
+	return e.EncodeAll(val, nil)
+}
+
+func compressLZ4(val []byte) []byte {
+	buf := new(bytes.Buffer)
+	w := lz4.NewWriter(buf)
+	w.Write(val)
+	w.Close()
+	return buf.Bytes()
+}
+
+func withHeader(codec Codec, val []byte) []byte {
+	return append([]byte{byte(codec)}, val...)
+}
+
+func TestParseCodec(t *testing.T) {
+	type Case struct {
+		input       string
+		expected    Codec
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{"none", None, false, "none"},
+		{"zstd", Zstd, false, "zstd"},
+		{"header", Header, false, "header"},
+		{"hoge", None, true, "unknown codec fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			codec, err := ParseCodec(c.input)
+
+			assert.Equal(t, c.expected, codec)
+			assert.Equal(t, c.expectError, err != nil)
+
+			if err == nil {
+				assert.Equal(t, c.input, codec.String())
+			}
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	val := []byte("hoge!hoge!hoge!hoge!")
+	hogeLZ4 := []byte("\x04\x22\x4d\x18\x64\x40\xa7\x05\x00\x00\x80hoge!\x00\x00\x00\x00\xcb\xd1\x2f\xb7")
+
+	type Case struct {
+		codec       Codec
+		input       []byte
+		expected    []byte
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{None, val, val, false, "none returns value as it is"},
+		{Gzip, compressGzip(val), val, false, "gzip decompresses"},
+		{Zstd, compressZstd(val), val, false, "zstd decompresses"},
+		{Snappy, snappy.Encode(nil, val), val, false, "snappy decompresses"},
+		{LZ4, hogeLZ4, []byte("hoge!"), false, "lz4 decompresses"},
+		{Gzip, val, nil, true, "gzip fails on invalid value"},
+		{Zstd, val, nil, true, "zstd fails on invalid value"},
+		{Snappy, []byte("\xff"), nil, true, "snappy fails on invalid value"},
+		{LZ4, val, nil, true, "lz4 fails on invalid value"},
+		{Header, withHeader(None, val), val, false, "header of none returns value as it is"},
+		{Header, withHeader(Zstd, compressZstd(val)), val, false, "header of zstd decompresses"},
+		{Header, withHeader(LZ4, hogeLZ4), []byte("hoge!"), false, "header of lz4 decompresses"},
+		{Header, []byte(""), nil, true, "missing header fails"},
+		{Header, withHeader(Header, val), nil, true, "header of header fails"},
+		{Header, withHeader(Codec(100), val), nil, true, "header of unknown codec fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := Decompress(c.codec, c.input)
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expected, v)
+		})
+	}
+}
+
+func TestDecompressFailsIfTooLarge(t *testing.T) {
+	fits := make([]byte, maxDecompressedSize)
+	large := make([]byte, maxDecompressedSize+1)
+
+	type Case struct {
+		codec    Codec
+		compress func([]byte) []byte
+		subtest  string
+	}
+	cases := []Case{
+		{Gzip, compressGzip, "gzip"},
+		{Zstd, compressZstd, "zstd"},
+		{Snappy, func(val []byte) []byte { return snappy.Encode(nil, val) }, "snappy"},
+		{LZ4, compressLZ4, "lz4"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := Decompress(c.codec, c.compress(fits))
+
+			assert.Nil(t, err)
+			assert.Equal(t, maxDecompressedSize, len(v))
+
+			v, err = Decompress(c.codec, c.compress(large))
+
+			assert.Nil(t, v)
+			assert.NotNil(t, err)
+		})
+	}
+}
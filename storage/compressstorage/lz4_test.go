@@ -0,0 +1,91 @@
+package compressstorage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lines returns content of lines.lz4, compressed in linked 64KB blocks with checksums and content size
+func lines() []byte {
+	buf := new(bytes.Buffer)
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(buf, "line %d\n", i%1000)
+	}
+	return buf.Bytes()
+}
+
+// random returns content of random.lz4, stored in an uncompressed block
+func random() []byte {
+	b := make([]byte, 1024)
+	x := uint32(1)
+	for i := range b {
+		x = (x*1103515245 + 12345) % (1 << 31)
+		b[i] = byte(x >> 16)
+	}
+	return b
+}
+
+func readFile(file string) []byte {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestDecompressLZ4(t *testing.T) {
+	hoge := readFile("hoge.lz4")
+	skippable := []byte("\x50\x2a\x4d\x18\x04\x00\x00\x00fuga")
+
+	type Case struct {
+		input    []byte
+		expected []byte
+		subtest  string
+	}
+	cases := []Case{
+		{hoge, []byte("hoge!"), "small frame"},
+		{readFile("lines.lz4"), lines(), "frame of linked blocks"},
+		{readFile("random.lz4"), random(), "frame of uncompressed block"},
+		{append(append(append([]byte{}, hoge...), skippable...), hoge...), []byte("hoge!hoge!"), "concatenated frames with skippable frame"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := Decompress(LZ4, c.input)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, v)
+		})
+	}
+}
+
+func TestDecompressLZ4Fails(t *testing.T) {
+	lines := readFile("lines.lz4")
+
+	type Case struct {
+		input   []byte
+		subtest string
+	}
+	cases := []Case{
+		{[]byte("\x04\x22\x4d"), "truncated magic"},
+		{[]byte("\x04\x22\x4d\x19\x64\x40\xa7"), "invalid magic"},
+		{[]byte("\x04\x22\x4d\x18\xa4\x40\xa7\x00\x00\x00\x00"), "unsupported version"},
+		{lines[:len(lines)/2], "truncated block"},
+		{lines[:len(lines)-4], "truncated content checksum"},
+		{[]byte("\x04\x22\x4d\x18\x64\x40\xa7\x03\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00"), "match offset out of output"},
+		{[]byte("\x50\x2a\x4d\x18\x08\x00\x00\x00fuga"), "truncated skippable frame"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := Decompress(LZ4, c.input)
+
+			assert.Nil(t, v)
+			assert.NotNil(t, err)
+		})
+	}
+}
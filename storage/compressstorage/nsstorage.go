@@ -0,0 +1,65 @@
+package compressstorage
+
+import (
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.NSItemStorage   = (*NSStorage)(nil)
+	_ storage.NSIterable      = (*NSStorage)(nil)
+	_ storage.NSRangeIterable = (*NSStorage)(nil)
+)
+
+// NSStorage represents a namespaced storage decompressing values from another namespaced storage
+type NSStorage struct {
+	Storage
+	nsproxy storage.NSStorage
+}
+
+// NewNS creates and returns a namespaced storage decompressing values found in proxy with codec
+func NewNS(proxy storage.NSStorage, codec Codec) *NSStorage {
+	return &NSStorage{*New(proxy, codec), proxy}
+}
+
+// GetNS finds a given ns+key in storage, decompresses its value, and returns
+func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	val, err := s.nsproxy.GetNS(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.decompress(val)
+}
+
+// GetItemNS finds a given ns+key in storage, decompresses its value, and returns with metadata from proxy if any
+func (s *NSStorage) GetItemNS(ns, key []byte) (*storage.Item, error) {
+	item, err := storage.GetItemNS(s.nsproxy, ns, key)
+	if err != nil {
+		return nil, err
+	}
+	if item.Value, err = s.decompress(item.Value); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Namespaces returns namespaces in proxy storage if it is iterable
+func (s *NSStorage) Namespaces() ([][]byte, error) {
+	return storage.Namespaces(s.nsproxy)
+}
+
+// KeysNS finds keys in given namespace in proxy storage if it is iterable
+func (s *NSStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.KeysNS(s.nsproxy, ns, prefix, start, limit)
+}
+
+// CountNS counts keys in given namespace in proxy storage if it is iterable
+func (s *NSStorage) CountNS(ns, prefix []byte) (int, error) {
+	return storage.CountNS(s.nsproxy, ns, prefix)
+}
+
+// RangeNS calls fn with decompressed items having keys in a range in namespace in proxy storage if it supports range
+func (s *NSStorage) RangeNS(ns, from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return s.rangeDecompressed(fn, func(f func([]byte, *storage.Item) bool) error {
+		return storage.RangeNS(s.nsproxy, ns, from, to, f)
+	})
+}
@@ -0,0 +1,126 @@
+package compressstorage
+
+import (
+	"io"
+
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.ItemStorage    = (*Storage)(nil)
+	_ storage.Iterable       = (*Storage)(nil)
+	_ storage.RangeIterable  = (*Storage)(nil)
+	_ storage.Generational   = (*Storage)(nil)
+	_ storage.MemoryReporter = (*Storage)(nil)
+	_ storage.StatsReporter  = (*Storage)(nil)
+	_ io.Closer              = (*Storage)(nil)
+)
+
+// Storage represents a storage decompressing values from another storage
+type Storage struct {
+	proxy storage.Storage
+	codec Codec
+}
+
+// New creates and returns a storage decompressing values found in proxy with codec
+func New(proxy storage.Storage, codec Codec) *Storage {
+	return &Storage{proxy, codec}
+}
+
+// Load loads data into storage
+func (s *Storage) Load(file string) error {
+	return s.proxy.Load(file)
+}
+
+// Generation returns the generation of proxy storage
+func (s *Storage) Generation() uint64 {
+	return storage.Generation(s.proxy)
+}
+
+// MemoryEstimate returns estimated bytes taken by proxy storage
+func (s *Storage) MemoryEstimate() uint64 {
+	return storage.MemoryEstimate(s.proxy)
+}
+
+// ReportStats calls fn with statistics of proxy storage
+func (s *Storage) ReportStats(fn func(string, uint64)) {
+	storage.ReportStats(s.proxy, fn)
+}
+
+// Close closes proxy storage
+func (s *Storage) Close() error {
+	return storage.Close(s.proxy)
+}
+
+// Get finds a given key in storage, decompresses its value, and returns
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	val, err := s.proxy.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.decompress(val)
+}
+
+// GetItem finds a given key in storage, decompresses its value, and returns with metadata from proxy if any
+func (s *Storage) GetItem(key []byte) (*storage.Item, error) {
+	p, ok := s.proxy.(storage.ItemStorage)
+	if !ok {
+		val, err := s.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		return &storage.Item{Value: val}, nil
+	}
+
+	item, err := p.GetItem(key)
+	if err != nil {
+		return nil, err
+	}
+	if item.Value, err = s.decompress(item.Value); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Range calls fn with decompressed items having keys in a range in proxy storage if it supports range
+func (s *Storage) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return s.rangeDecompressed(fn, func(f func([]byte, *storage.Item) bool) error {
+		return storage.Range(s.proxy, from, to, f)
+	})
+}
+
+// rangeDecompressed calls fn with items found by iterate after decompressing, and stops at an item failing decompression
+func (s *Storage) rangeDecompressed(
+	fn func([]byte, *storage.Item) bool,
+	iterate func(func([]byte, *storage.Item) bool) error,
+) error {
+	var derr error
+	err := iterate(func(key []byte, item *storage.Item) bool {
+		if item.Value, derr = s.decompress(item.Value); derr != nil {
+			return false
+		}
+		return fn(key, item)
+	})
+	if err != nil {
+		return err
+	}
+	return derr
+}
+
+func (s *Storage) decompress(val []byte) ([]byte, error) {
+	v, err := Decompress(s.codec, val)
+	if err != nil {
+		return nil, storage.InternalError("failed decompressing value: " + err.Error())
+	}
+	return v, nil
+}
+
+// Keys finds keys in proxy storage if it is iterable
+func (s *Storage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.Keys(s.proxy, prefix, start, limit)
+}
+
+// Count counts keys in proxy storage if it is iterable
+func (s *Storage) Count(prefix []byte) (int, error) {
+	return storage.Count(s.proxy, prefix)
+}
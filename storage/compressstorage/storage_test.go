@@ -0,0 +1,231 @@
+package compressstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.NSStorage     = (*testStorage)(nil)
+	_ storage.ItemStorage   = (*testItemStorage)(nil)
+	_ storage.NSItemStorage = (*testItemStorage)(nil)
+)
+
+type testStorage map[string][]byte
+
+func (s testStorage) Load(file string) error {
+	return nil
+}
+
+func (s testStorage) Get(key []byte) ([]byte, error) {
+	if v, ok := s[string(key)]; ok {
+		return v, nil
+	}
+	return nil, storage.KeyNotFoundError(key)
+}
+
+func (s testStorage) GetNS(ns, key []byte) ([]byte, error) {
+	return s.Get(append(append([]byte{}, ns...), key...))
+}
+
+type testItemStorage struct {
+	testStorage
+}
+
+func (s testItemStorage) GetItem(key []byte) (*storage.Item, error) {
+	v, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.Item{Value: v, Flags: 1}, nil
+}
+
+func (s testItemStorage) GetItemNS(ns, key []byte) (*storage.Item, error) {
+	return s.GetItem(append(append([]byte{}, ns...), key...))
+}
+
+func newTestStorage() testStorage {
+	return testStorage{
+		"hoge":     compressZstd([]byte("hoge!")),
+		"fuga":     []byte("fuga!"),
+		"hogefuga": withHeader(Gzip, compressGzip([]byte("hogefuga!"))),
+	}
+}
+
+func TestGet(t *testing.T) {
+	type Case struct {
+		codec       Codec
+		input       []byte
+		expected    []byte
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{Zstd, []byte("hoge"), []byte("hoge!"), false, "compressed value is decompressed"},
+		{Zstd, []byte("fuga"), nil, true, "uncompressed value fails"},
+		{Zstd, []byte("foo"), nil, true, "non-existing key fails"},
+		{Header, []byte("hogefuga"), []byte("hogefuga!"), false, "value with header is decompressed"},
+		{None, []byte("fuga"), []byte("fuga!"), false, "value is returned as it is"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := New(newTestStorage(), c.codec)
+			v, err := s.Get(c.input)
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expected, v)
+		})
+	}
+}
+
+func TestGetItem(t *testing.T) {
+	type Case struct {
+		proxy    storage.Storage
+		expected *storage.Item
+		subtest  string
+	}
+	cases := []Case{
+		{
+			newTestStorage(),
+			&storage.Item{Value: []byte("hoge!")},
+			"storage without metadata returns item without flags",
+		},
+		{
+			testItemStorage{newTestStorage()},
+			&storage.Item{Value: []byte("hoge!"), Flags: 1},
+			"storage with metadata returns item with flags",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := New(c.proxy, Zstd)
+			item, err := s.GetItem([]byte("hoge"))
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, item)
+
+			item, err = s.GetItem([]byte("fuga"))
+
+			assert.Nil(t, item)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestGetNS(t *testing.T) {
+	s := NewNS(newTestStorage(), Header)
+
+	v, err := s.GetNS([]byte("hoge"), []byte("fuga"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hogefuga!"), v)
+
+	v, err = s.GetNS([]byte("hoge"), []byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestGetItemNS(t *testing.T) {
+	type Case struct {
+		proxy    storage.NSStorage
+		expected *storage.Item
+		subtest  string
+	}
+	cases := []Case{
+		{
+			newTestStorage(),
+			&storage.Item{Value: []byte("hogefuga!")},
+			"storage without metadata returns item without flags",
+		},
+		{
+			testItemStorage{newTestStorage()},
+			&storage.Item{Value: []byte("hogefuga!"), Flags: 1},
+			"storage with metadata returns item with flags",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := NewNS(c.proxy, Header)
+			item, err := s.GetItemNS([]byte("hoge"), []byte("fuga"))
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, item)
+
+			item, err = s.GetItemNS([]byte("hoge"), []byte("hoge"))
+
+			assert.Nil(t, item)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+type testIterableStorage struct {
+	testStorage
+}
+
+func (s testIterableStorage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return [][]byte{prefix, start}, nil
+}
+
+func (s testIterableStorage) Count(prefix []byte) (int, error) {
+	return len(s.testStorage), nil
+}
+
+func TestKeysAndCount(t *testing.T) {
+	s := New(testIterableStorage{newTestStorage()}, Zstd)
+
+	keys, err := s.Keys([]byte("ho"), []byte("hoge"), 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("ho"), []byte("hoge")}, keys)
+
+	n, err := s.Count(nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	s = New(newTestStorage(), Zstd)
+	_, err = s.Keys(nil, nil, 0)
+
+	assert.NotNil(t, err)
+}
+
+type testRangeStorage struct {
+	testStorage
+}
+
+// Range calls fn with all items regardless of range, in no order
+func (s testRangeStorage) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	for k, v := range s.testStorage {
+		if !fn([]byte(k), &storage.Item{Value: v}) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestRange(t *testing.T) {
+	s := New(testRangeStorage{testStorage{"hoge": compressZstd([]byte("hoge!"))}}, Zstd)
+
+	var actual []string
+	err := s.Range(nil, nil, func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hoge=hoge!"}, actual)
+
+	s = New(testRangeStorage{testStorage{"fuga": []byte("fuga!")}}, Zstd)
+	err = s.Range(nil, nil, func(key []byte, item *storage.Item) bool {
+		return true
+	})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,145 @@
+package jsonstorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// arenaChunkSize defines the size of a chunk to allocate values from
+const arenaChunkSize = 1 << 20
+
+// entryOverhead approximates bytes taken by a map entry besides its key and value, and by its key in sorted keys
+const entryOverhead = 64
+
+// arena allocates values out of large chunks, so that millions of small values don't cost as many allocations
+type arena struct {
+	chunk []byte
+	bytes uint64
+}
+
+// copy copies given bytes into arena, and returns the copy
+func (a *arena) copy(b []byte) []byte {
+	if len(b) > arenaChunkSize/4 {
+		a.bytes += uint64(len(b))
+		return append([]byte(nil), b...)
+	}
+	if cap(a.chunk)-len(a.chunk) < len(b) {
+		a.chunk = make([]byte, 0, arenaChunkSize)
+		a.bytes += arenaChunkSize
+	}
+	start := len(a.chunk)
+	a.chunk = append(a.chunk, b...)
+	return a.chunk[start:len(a.chunk):len(a.chunk)]
+}
+
+// dataBuilder builds Data out of JSON values, and estimates memory taken by built data
+type dataBuilder struct {
+	valueMode ValueMode
+	arena     *arena
+	keyBytes  uint64
+	entries   uint64
+}
+
+func newDataBuilder(mode ValueMode) *dataBuilder {
+	return &dataBuilder{mode, new(arena), 0, 0}
+}
+
+// readObject reads a JSON object of key-values from decoder into a new Data
+func (b *dataBuilder) readObject(decoder *json.Decoder) (Data, error) {
+	data := make(Data)
+	var raw json.RawMessage
+	err := walkObject(decoder, func(key string) error {
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		val, err := DecodeValue(raw, b.valueMode)
+		if err != nil {
+			return fmt.Errorf("invalid value for key '%s': %s", key, err.Error())
+		}
+		data[key] = b.arena.copy(val)
+		b.keyBytes += uint64(len(key))
+		b.entries++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// memoryEstimate returns estimated bytes taken by data built so far
+func (b *dataBuilder) memoryEstimate() uint64 {
+	return b.arena.bytes + b.keyBytes + b.entries*entryOverhead
+}
+
+// walkObject reads a JSON object token by token, and calls fn on each key with decoder positioned at its value.
+// fn must consume the value.
+func walkObject(decoder *json.Decoder, fn func(key string) error) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key but got %v", tok)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, '}')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected '%s' but got %v", delim, tok)
+	}
+	return nil
+}
+
+// DecodeValue decodes a JSON string into its content, or any other JSON value into canonical encoding if allowed by mode.
+// Returned bytes may share memory with raw.
+func DecodeValue(raw json.RawMessage, mode ValueMode) ([]byte, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		// A string without escapes is already validated by decoder, and its content is as it is
+		if bytes.IndexByte(raw, '\\') < 0 {
+			return raw[1 : len(raw)-1], nil
+		}
+		var str string
+		if err := json.Unmarshal(raw, &str); err != nil {
+			return nil, err
+		}
+		return []byte(str), nil
+	}
+	if mode != RawValues {
+		return nil, fmt.Errorf("expected a string but got %s", string(raw))
+	}
+	return canonicalJSON(raw)
+}
+
+// canonicalJSON re-encodes a JSON value compactly with object keys sorted, keeping numbers as they are
+func canonicalJSON(raw json.RawMessage) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,61 @@
+package jsonstorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArenaCopy(t *testing.T) {
+	a := new(arena)
+
+	src := []byte("hoge")
+	v1 := a.copy(src)
+	v2 := a.copy([]byte("fuga"))
+	src[0] = 'H'
+
+	assert.Equal(t, []byte("hoge"), v1)
+	assert.Equal(t, []byte("fuga"), v2)
+	assert.Equal(t, 4, cap(v1), "a copy is capped not to overwrite next copy on append")
+	assert.Equal(t, uint64(arenaChunkSize), a.bytes)
+
+	large := bytes.Repeat([]byte("a"), arenaChunkSize/2)
+	v3 := a.copy(large)
+
+	assert.Equal(t, large, v3)
+	assert.Equal(t, uint64(arenaChunkSize+arenaChunkSize/2), a.bytes)
+}
+
+func TestWalkObject(t *testing.T) {
+	type Case struct {
+		input        string
+		expectedKeys []string
+		expectError  bool
+		subtest      string
+	}
+	cases := []Case{
+		{`{"a": 1, "b": {"c": 2}}`, []string{"a", "b"}, false, "object succeeds"},
+		{`{}`, nil, false, "empty object succeeds"},
+		{`null`, nil, true, "null fails"},
+		{`["a"]`, nil, true, "array fails"},
+		{`{"a": 1`, []string{"a"}, true, "unterminated object fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(c.input))
+			var keys []string
+			err := walkObject(decoder, func(key string) error {
+				keys = append(keys, key)
+				var v interface{}
+				return decoder.Decode(&v)
+			})
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expectedKeys, keys)
+		})
+	}
+}
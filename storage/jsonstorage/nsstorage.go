@@ -1,16 +1,28 @@
 package jsonstorage
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
 	"github.com/yowcow/goromdb/storage"
 )
 
+// NSData represents a namespaced data
+type NSData map[string]Data
+
+// nsSortedData represents namespaced data with keys sorted in each namespace
+type nsSortedData map[string]*sortedData
+
 // NSStorage represents a namespaced JSON storage
 type NSStorage struct {
 	Storage
 }
 
 var (
-	_ storage.NSStorage = (*NSStorage)(nil)
+	_ storage.NSStorage  = (*NSStorage)(nil)
+	_ storage.NSIterable = (*NSStorage)(nil)
 )
 
 // NewNS creates and returns a namespaced JSON storage
@@ -18,6 +30,38 @@ func NewNS(gzipped bool) *NSStorage {
 	return &NSStorage{*New(gzipped)}
 }
 
+// Load loads namespaced data into storage
+func (s *NSStorage) Load(file string) error {
+	nsdata := make(nsSortedData)
+	builder := newDataBuilder(s.valueMode)
+	err := s.readFile(file, func(decoder *json.Decoder) error {
+		return walkObject(decoder, func(ns string) error {
+			data, err := builder.readObject(decoder)
+			if err != nil {
+				return fmt.Errorf("in namespace '%s': %s", ns, err.Error())
+			}
+			nsdata[ns] = newSortedData(data)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Store(nsdata)
+	atomic.StoreUint64(s.memory, builder.memoryEstimate())
+	atomic.AddUint64(s.generation, 1)
+	return nil
+}
+
+// Get always fails, since any key belongs to a namespace
+func (s *NSStorage) Get(key []byte) ([]byte, error) {
+	return nil, storage.KeyNotFoundError(key)
+}
+
 // GetNS finds a given key in given namespace, and returns its value
 func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
 	s.mux.RLock()
@@ -28,16 +72,81 @@ func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
 		return nil, storage.KeyNotFoundError(key)
 	}
 
-	data := ptr.(Data)
-	nsdata, ok := data[string(ns)]
+	nsdata, ok := ptr.(nsSortedData)[string(ns)]
 	if !ok {
 		return nil, storage.KeyNotFoundError(ns)
 	}
 
-	v, ok := nsdata.(map[string]interface{})[string(key)]
+	v, ok := nsdata.Data[string(key)]
 	if !ok {
 		return nil, storage.KeyNotFoundError(key)
 	}
 
-	return []byte(v.(string)), nil
+	return v, nil
+}
+
+// Keys always finds nothing, since any key belongs to a namespace
+func (s *NSStorage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return nil, nil
+}
+
+// Count always counts nothing, since any key belongs to a namespace
+func (s *NSStorage) Count(prefix []byte) (int, error) {
+	return 0, nil
+}
+
+// Namespaces returns namespaces in data in sorted order
+func (s *NSStorage) Namespaces() ([][]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ptr := s.data.Load()
+	if ptr == nil {
+		return nil, nil
+	}
+
+	var names []string
+	for ns := range ptr.(nsSortedData) {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+
+	var nss [][]byte
+	for _, ns := range names {
+		nss = append(nss, []byte(ns))
+	}
+	return nss, nil
+}
+
+// KeysNS finds keys having prefix in given namespace, and returns up to limit keys starting at start
+func (s *NSStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	data, err := s.getData(ns)
+	if err != nil {
+		return nil, err
+	}
+	return data.find(prefix, start, limit), nil
+}
+
+// CountNS counts keys having prefix in given namespace
+func (s *NSStorage) CountNS(ns, prefix []byte) (int, error) {
+	data, err := s.getData(ns)
+	if err != nil {
+		return 0, err
+	}
+	return data.count(prefix), nil
+}
+
+func (s *NSStorage) getData(ns []byte) (*sortedData, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ptr := s.data.Load()
+	if ptr == nil {
+		return nil, storage.BucketNotFoundError(ns)
+	}
+	data, ok := ptr.(nsSortedData)[string(ns)]
+	if !ok {
+		return nil, storage.BucketNotFoundError(ns)
+	}
+	return data, nil
 }
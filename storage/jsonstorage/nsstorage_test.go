@@ -56,3 +56,102 @@ func TestGetNS(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadNS(t *testing.T) {
+	type Case struct {
+		input       string
+		valueMode   ValueMode
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{
+			"valid-ns.json",
+			StringValues,
+			false,
+			"loading valid json succeeds",
+		},
+		{
+			"valid.json",
+			StringValues,
+			true,
+			"loading json without namespace fails",
+		},
+		{
+			"invalid-ns.json",
+			StringValues,
+			true,
+			"loading json with non-object namespace fails",
+		},
+		{
+			"valid-ns-any.json",
+			StringValues,
+			true,
+			"loading json with non-string values fails",
+		},
+		{
+			"valid-ns-any.json",
+			RawValues,
+			false,
+			"loading json with non-string values succeeds with raw values",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := NewNS(false)
+			s.SetValueMode(c.valueMode)
+			err := s.Load(c.input)
+			assert.Equal(t, c.expectError, err != nil)
+		})
+	}
+}
+
+func TestGetNSWithRawValues(t *testing.T) {
+	s := NewNS(false)
+	s.SetValueMode(RawValues)
+	err := s.Load("valid-ns-any.json")
+
+	assert.Nil(t, err)
+
+	v, err := s.GetNS([]byte("hoge"), []byte("number"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("123"), v)
+
+	v, err = s.Get([]byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestNamespacesAndKeysNS(t *testing.T) {
+	s := NewNS(false)
+	err := s.Load("valid-ns.json")
+
+	assert.Nil(t, err)
+
+	names, err := s.Namespaces()
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("hoge")}, names)
+
+	keys, err := s.KeysNS([]byte("hoge"), []byte("fu"), nil, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("fuga")}, keys)
+
+	n, err := s.CountNS([]byte("foo"), nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	_, err = s.KeysNS([]byte("fuga"), nil, nil, 0)
+
+	assert.NotNil(t, err)
+
+	keys, err = s.Keys(nil, nil, 0)
+
+	assert.Nil(t, err)
+	assert.Nil(t, keys)
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -12,27 +14,70 @@ import (
 )
 
 var (
-	_ storage.Storage = (*Storage)(nil)
+	_ storage.Storage        = (*Storage)(nil)
+	_ storage.MemoryReporter = (*Storage)(nil)
+	_ storage.Iterable       = (*Storage)(nil)
+	_ storage.Generational   = (*Storage)(nil)
+)
+
+// ValueMode defines how JSON values other than strings are handled
+type ValueMode int
+
+const (
+	// StringValues accepts only string values, and fails loading data with any other value
+	StringValues ValueMode = iota
+	// RawValues accepts values of any JSON type, and returns non-string values in canonical JSON encoding
+	RawValues
 )
 
 // Data represents a data
-type Data map[string]interface{}
+type Data map[string][]byte
+
+// sortedData represents data with its keys sorted at load, so that a page of keys is found by binary search
+type sortedData struct {
+	Data
+	keys []string
+}
+
+func newSortedData(data Data) *sortedData {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return &sortedData{data, keys}
+}
 
 // Storage represents a JSON storage
 type Storage struct {
-	gzipped bool
-	data    *atomic.Value
-	mux     *sync.RWMutex
+	gzipped    bool
+	valueMode  ValueMode
+	data       *atomic.Value
+	memory     *uint64
+	mux        *sync.RWMutex
+	generation *uint64
 }
 
 // New creates and returns a storage
 func New(gzipped bool) *Storage {
-	return &Storage{gzipped, new(atomic.Value), new(sync.RWMutex)}
+	return &Storage{gzipped, StringValues, new(atomic.Value), new(uint64), new(sync.RWMutex), new(uint64)}
+}
+
+// SetValueMode sets how values other than strings are handled.
+// This must be called before loading any data.
+func (s *Storage) SetValueMode(mode ValueMode) {
+	s.valueMode = mode
 }
 
 // Load loads data into storage
 func (s *Storage) Load(file string) error {
-	data, err := s.openFile(file)
+	var data Data
+	builder := newDataBuilder(s.valueMode)
+	err := s.readFile(file, func(decoder *json.Decoder) error {
+		var err error
+		data, err = builder.readObject(decoder)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -41,28 +86,26 @@ func (s *Storage) Load(file string) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.data.Store(data)
+	s.data.Store(newSortedData(data))
+	atomic.StoreUint64(s.memory, builder.memoryEstimate())
+	atomic.AddUint64(s.generation, 1)
 	return nil
 }
 
-func (s Storage) openFile(file string) (Data, error) {
+// readFile opens file, and calls fn with a decoder streaming its content
+func (s Storage) readFile(file string, fn func(*json.Decoder) error) error {
 	fi, err := os.Open(file)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer fi.Close()
 
 	r, err := s.newReader(fi)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	decoder := json.NewDecoder(r)
-	var data Data
-	if err := decoder.Decode(&data); err != nil {
-		return nil, err
-	}
-	return data, nil
+	return fn(json.NewDecoder(r))
 }
 
 func (s Storage) newReader(rdr io.Reader) (io.Reader, error) {
@@ -76,6 +119,16 @@ func (s Storage) newReader(rdr io.Reader) (io.Reader, error) {
 	return rdr, nil
 }
 
+// MemoryEstimate returns estimated bytes taken by loaded data
+func (s Storage) MemoryEstimate() uint64 {
+	return atomic.LoadUint64(s.memory)
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
 // Get finds a given key in data, and returns its value
 func (s Storage) Get(key []byte) ([]byte, error) {
 	s.mux.RLock()
@@ -85,10 +138,59 @@ func (s Storage) Get(key []byte) ([]byte, error) {
 	if ptr == nil {
 		return nil, storage.KeyNotFoundError(key)
 	}
-	data := ptr.(Data)
-	k := string(key)
-	if v, ok := data[k]; ok {
-		return []byte(v.(string)), nil
+	data := ptr.(*sortedData)
+	if v, ok := data.Data[string(key)]; ok {
+		return v, nil
 	}
 	return nil, storage.KeyNotFoundError(key)
 }
+
+// Keys finds keys having prefix in data, and returns up to limit keys starting at start
+func (s Storage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ptr := s.data.Load()
+	if ptr == nil {
+		return nil, nil
+	}
+	return ptr.(*sortedData).find(prefix, start, limit), nil
+}
+
+// Count counts keys having prefix in data
+func (s Storage) Count(prefix []byte) (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ptr := s.data.Load()
+	if ptr == nil {
+		return 0, nil
+	}
+	return ptr.(*sortedData).count(prefix), nil
+}
+
+// find returns up to limit keys having prefix, starting at start
+func (d *sortedData) find(prefix, start []byte, limit int) [][]byte {
+	p, st := string(prefix), string(start)
+	if st < p {
+		st = p
+	}
+	var keys [][]byte
+	for i := sort.SearchStrings(d.keys, st); i < len(d.keys); i++ {
+		if !strings.HasPrefix(d.keys[i], p) || (limit > 0 && len(keys) >= limit) {
+			break
+		}
+		keys = append(keys, []byte(d.keys[i]))
+	}
+	return keys
+}
+
+// count counts keys having prefix, which are next to each other in sorted keys
+func (d *sortedData) count(prefix []byte) int {
+	p := string(prefix)
+	i := sort.SearchStrings(d.keys, p)
+	n := sort.Search(len(d.keys)-i, func(n int) bool {
+		return !strings.HasPrefix(d.keys[i+n], p)
+	})
+	return n
+}
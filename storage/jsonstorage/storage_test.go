@@ -24,6 +24,24 @@ func TestLoad(t *testing.T) {
 		subtest     string
 	}
 	cases := []Case{
+		{
+			false,
+			"null.json",
+			true,
+			"loading null fails",
+		},
+		{
+			false,
+			"array.json",
+			true,
+			"loading array fails",
+		},
+		{
+			false,
+			"valid-any.json",
+			true,
+			"loading json with non-string values fails",
+		},
 		{
 			false,
 			"./",
@@ -97,3 +115,195 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadWithRawValues(t *testing.T) {
+	type Case struct {
+		input       string
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{
+			"valid-any.json",
+			false,
+			"loading json with non-string values succeeds",
+		},
+		{
+			"null.json",
+			true,
+			"loading null fails",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := New(false)
+			s.SetValueMode(RawValues)
+			err := s.Load(c.input)
+			assert.Equal(t, c.expectError, err != nil)
+		})
+	}
+}
+
+func TestGetWithRawValues(t *testing.T) {
+	s := New(false)
+	s.SetValueMode(RawValues)
+	err := s.Load("valid-any.json")
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		input       []byte
+		expectedVal []byte
+		subtest     string
+	}
+	cases := []Case{
+		{
+			[]byte("string"),
+			[]byte("hoge"),
+			"string returns its content",
+		},
+		{
+			[]byte("number"),
+			[]byte("1.50"),
+			"number returns as it is",
+		},
+		{
+			[]byte("bool"),
+			[]byte("true"),
+			"bool returns in json",
+		},
+		{
+			[]byte("null"),
+			[]byte("null"),
+			"null returns in json",
+		},
+		{
+			[]byte("array"),
+			[]byte(`[1,"two",{"a":1,"b":2}]`),
+			"array returns in canonical json",
+		},
+		{
+			[]byte("object"),
+			[]byte(`{"a":[],"z":"<z>"}`),
+			"object returns in canonical json",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.Get(c.input)
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedVal, v)
+		})
+	}
+}
+
+func TestGetEscapedString(t *testing.T) {
+	s := New(false)
+	err := s.Load("valid.json")
+
+	assert.Nil(t, err)
+
+	v, err := s.Get([]byte("escaped"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("\"hoge\"\n\u3042"), v)
+}
+
+func TestMemoryEstimate(t *testing.T) {
+	s := New(false)
+
+	assert.Equal(t, uint64(0), s.MemoryEstimate())
+
+	err := s.Load("valid.json")
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(arenaChunkSize+len("hogefugaescaped")+3*entryOverhead), s.MemoryEstimate())
+}
+
+func TestGeneration(t *testing.T) {
+	s := New(false)
+
+	assert.Equal(t, uint64(0), s.Generation())
+	assert.Nil(t, s.Load("valid.json"))
+	assert.Equal(t, uint64(1), s.Generation())
+	assert.NotNil(t, s.Load("hoge.json"))
+	assert.Equal(t, uint64(1), s.Generation())
+
+	ns := NewNS(false)
+
+	assert.Nil(t, ns.Load("valid-ns.json"))
+	assert.Equal(t, uint64(1), ns.Generation())
+}
+
+func TestKeysAndCount(t *testing.T) {
+	s := New(false)
+	keys, err := s.Keys(nil, nil, 0)
+
+	assert.Nil(t, err)
+	assert.Nil(t, keys)
+
+	s.Load(sampleDataFile)
+
+	type Case struct {
+		prefix        []byte
+		start         []byte
+		limit         int
+		expectedKeys  [][]byte
+		expectedCount int
+		subtest       string
+	}
+	cases := []Case{
+		{
+			nil, nil, 0,
+			[][]byte{[]byte("bar"), []byte("buz"), []byte("foo"), []byte("fuga"), []byte("hoge")},
+			5,
+			"all keys in order",
+		},
+		{
+			[]byte("f"), []byte("fp"), 0,
+			[][]byte{[]byte("fuga")},
+			2,
+			"keys having prefix starting at start",
+		},
+		{
+			nil, []byte("buz"), 2,
+			[][]byte{[]byte("buz"), []byte("foo")},
+			5,
+			"keys up to limit starting at start",
+		},
+		{
+			[]byte("fu"), []byte("a"), 0,
+			[][]byte{[]byte("fuga")},
+			1,
+			"keys having prefix with start before prefix",
+		},
+		{
+			[]byte("f"), []byte("g"), 0,
+			nil,
+			2,
+			"no key having prefix after start",
+		},
+		{
+			[]byte("x"), nil, 0,
+			nil,
+			0,
+			"no key having prefix",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			keys, err := s.Keys(c.prefix, c.start, c.limit)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedKeys, keys)
+
+			n, err := s.Count(c.prefix)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expectedCount, n)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package leveldbstorage
+
+import (
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/nskey"
+)
+
+var (
+	_ storage.NSStorage = (*NSStorage)(nil)
+)
+
+// NSStorage represents a namespaced LevelDB storage, having keys prefixed by namespace and separator
+type NSStorage struct {
+	Storage
+	enc nskey.Separator
+}
+
+// NewNS creates and returns a storage with given separator between namespace and key.
+// A namespace containing the separator fails, and an empty separator makes a namespace a plain key prefix.
+func NewNS(sep string) *NSStorage {
+	return &NSStorage{*New(), nskey.Separator(sep)}
+}
+
+// GetNS finds a given ns+separator+key in db, and returns its value
+func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	fullKey, err := s.enc.Encode(ns, key)
+	if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+	return s.Get(fullKey)
+}
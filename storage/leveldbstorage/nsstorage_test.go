@@ -0,0 +1,80 @@
+package leveldbstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleNSDBFile = "../../_data/store/sample-ns-leveldb.db"
+
+func TestNewNS(t *testing.T) {
+	NewNS(":")
+}
+
+func TestGetNS(t *testing.T) {
+	type Case struct {
+		subtest       string
+		file          string
+		separator     string
+		input         [2][]byte
+		expectedValue []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{
+			"namespace and key exists with separator",
+			sampleNSDBFile,
+			":",
+			[2][]byte{[]byte("ns1"), []byte("hoge")},
+			[]byte("hoge1"),
+			false,
+		},
+		{
+			"namespace exists but key not exists with separator",
+			sampleNSDBFile,
+			":",
+			[2][]byte{[]byte("ns2"), []byte("fuga")},
+			nil,
+			true,
+		},
+		{
+			"namespace and key not exist with separator",
+			sampleNSDBFile,
+			":",
+			[2][]byte{[]byte("foo"), []byte("bar")},
+			nil,
+			true,
+		},
+		{
+			"namespace containing separator fails",
+			sampleNSDBFile,
+			":",
+			[2][]byte{[]byte("ns1:hoge"), []byte("")},
+			nil,
+			true,
+		},
+		{
+			"namespace and key exists as prefix",
+			sampleDBFile,
+			"",
+			[2][]byte{[]byte("ho"), []byte("ge")},
+			[]byte("hoge!"),
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := NewNS(c.separator)
+			err := s.Load(c.file)
+
+			assert.Nil(t, err)
+
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedValue, v)
+			assert.True(t, c.errorExpected == (err != nil))
+		})
+	}
+}
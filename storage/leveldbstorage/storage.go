@@ -0,0 +1,97 @@
+package leveldbstorage
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.Storage      = (*Storage)(nil)
+	_ storage.Generational = (*Storage)(nil)
+	_ io.Closer            = (*Storage)(nil)
+)
+
+// Storage represents a LevelDB storage
+type Storage struct {
+	db         *atomic.Value
+	mux        *sync.RWMutex
+	generation *uint64
+}
+
+// New creates and returns a storage
+func New() *Storage {
+	return &Storage{new(atomic.Value), new(sync.RWMutex), new(uint64)}
+}
+
+// Load loads a new db handle of a database directory into storage, and closes old db handle if exists
+func (s *Storage) Load(dir string) error {
+	newDB, err := openDB(dir)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldDB := s.getDB()
+	s.db.Store(newDB)
+	atomic.AddUint64(s.generation, 1)
+	if oldDB != nil {
+		oldDB.Close()
+	}
+	return nil
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+// Close waits for reads in progress, and closes db handle if exists
+func (s *Storage) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldDB := s.getDB()
+	if oldDB == nil {
+		return nil
+	}
+	s.db.Store((*leveldb.DB)(nil))
+	return oldDB.Close()
+}
+
+func (s *Storage) getDB() *leveldb.DB {
+	if ptr := s.db.Load(); ptr != nil {
+		return ptr.(*leveldb.DB)
+	}
+	return nil
+}
+
+func openDB(dir string) (*leveldb.DB, error) {
+	return leveldb.OpenFile(dir, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+}
+
+// Get finds a given key in db, and returns its value
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	db := s.getDB()
+	if db == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+
+	v, err := db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, storage.KeyNotFoundError(key)
+	} else if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+
+	return v, nil
+}
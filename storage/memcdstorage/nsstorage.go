@@ -1,21 +1,32 @@
 package memcdstorage
 
 import (
+	"io"
+
 	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/nskey"
 )
 
 var (
-	_ storage.NSStorage = (*NSStorage)(nil)
+	_ storage.NSItemStorage   = (*NSStorage)(nil)
+	_ storage.Iterable        = (*NSStorage)(nil)
+	_ storage.NSIterable      = (*NSStorage)(nil)
+	_ storage.NSRangeIterable = (*NSStorage)(nil)
+	_ storage.Generational    = (*NSStorage)(nil)
+	_ storage.MemoryReporter  = (*NSStorage)(nil)
+	_ storage.StatsReporter   = (*NSStorage)(nil)
+	_ io.Closer               = (*NSStorage)(nil)
 )
 
-// NSStorage represents a NSStoragbe for memcdstorage
+// NSStorage represents a NSStorage for memcdstorage, having values serialized with namespaces and keys encoded into single keys
 type NSStorage struct {
 	proxy storage.NSStorage
+	enc   nskey.Encoding
 }
 
-// NewNS returns a new NSStorage
-func NewNS(proxy storage.NSStorage) *NSStorage {
-	return &NSStorage{proxy}
+// NewNS returns a new NSStorage with given encoding of namespace and key, which must be the same as proxy's
+func NewNS(proxy storage.NSStorage, enc nskey.Encoding) *NSStorage {
+	return &NSStorage{proxy, enc}
 }
 
 // Load loads data into storage
@@ -23,6 +34,26 @@ func (s *NSStorage) Load(file string) error {
 	return s.proxy.Load(file)
 }
 
+// Generation returns the generation of proxy storage
+func (s *NSStorage) Generation() uint64 {
+	return storage.Generation(s.proxy)
+}
+
+// MemoryEstimate returns estimated bytes taken by proxy storage
+func (s *NSStorage) MemoryEstimate() uint64 {
+	return storage.MemoryEstimate(s.proxy)
+}
+
+// ReportStats calls fn with statistics of proxy storage
+func (s *NSStorage) ReportStats(fn func(string, uint64)) {
+	storage.ReportStats(s.proxy, fn)
+}
+
+// Close closes proxy storage
+func (s *NSStorage) Close() error {
+	return storage.Close(s.proxy)
+}
+
 // Get finds a given key in storage, deserialize its value into memcachedb format, and returns
 func (s *NSStorage) Get(key []byte) ([]byte, error) {
 	val, err := s.proxy.Get(key)
@@ -34,9 +65,62 @@ func (s *NSStorage) Get(key []byte) ([]byte, error) {
 
 // GetNS finds a given ns+key in storage, deserialize its value into memcachedb format, and returns
 func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	fullKey, err := s.enc.Encode(ns, key)
+	if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
 	val, err := s.proxy.GetNS(ns, key)
 	if err != nil {
 		return nil, err
 	}
-	return unmarshalMemcachedbBytes(key, val)
+	return unmarshalMemcachedbBytes(fullKey, val)
+}
+
+// GetItemNS finds a given ns+key in storage, deserialize its value and flags in memcachedb format, and returns
+func (s *NSStorage) GetItemNS(ns, key []byte) (*storage.Item, error) {
+	fullKey, err := s.enc.Encode(ns, key)
+	if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+	val, err := s.proxy.GetNS(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalMemcachedbItem(fullKey, val)
+}
+
+// Keys finds keys in storage if it is iterable
+func (s *NSStorage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.Keys(s.proxy, prefix, start, limit)
+}
+
+// Count counts keys in storage if it is iterable
+func (s *NSStorage) Count(prefix []byte) (int, error) {
+	return storage.Count(s.proxy, prefix)
+}
+
+// Namespaces returns namespaces in storage if it is iterable
+func (s *NSStorage) Namespaces() ([][]byte, error) {
+	return storage.Namespaces(s.proxy)
+}
+
+// KeysNS finds keys in given namespace in storage if it is iterable
+func (s *NSStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.KeysNS(s.proxy, ns, prefix, start, limit)
+}
+
+// CountNS counts keys in given namespace in storage if it is iterable
+func (s *NSStorage) CountNS(ns, prefix []byte) (int, error) {
+	return storage.CountNS(s.proxy, ns, prefix)
+}
+
+// RangeNS calls fn with items deserialized in memcachedb format having keys in a range in namespace in storage if it supports range
+func (s *NSStorage) RangeNS(ns, from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	prefix, err := s.enc.Prefix(ns)
+	if err != nil {
+		return storage.InternalError(err.Error())
+	}
+	return storage.RangeNS(s.proxy, ns, from, to, unmarshalEach(fn, func(key []byte) []byte {
+		return append(append([]byte{}, prefix...), key...)
+	}))
 }
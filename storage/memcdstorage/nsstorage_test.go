@@ -4,17 +4,21 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
 	"github.com/yowcow/goromdb/storage/bdbstorage"
+	"github.com/yowcow/goromdb/storage/nskey"
 )
 
+var sampleNSDBFile = "../../_data/store/sample-ns-memcachedb-bdb.db"
+
 func TestNewNS(t *testing.T) {
-	p := bdbstorage.NewNS()
-	NewNS(p)
+	p := bdbstorage.NewNS(nskey.Concat)
+	NewNS(p, nskey.Concat)
 }
 
 func TestGetNS(t *testing.T) {
-	p := bdbstorage.NewNS()
-	s := NewNS(p)
+	p := bdbstorage.NewNS(nskey.Concat)
+	s := NewNS(p, nskey.Concat)
 	err := s.Load(sampleDBFile)
 
 	assert.Nil(t, err)
@@ -55,3 +59,51 @@ func TestGetNS(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNSWithEncoding(t *testing.T) {
+	enc := nskey.Separator(":")
+	s := NewNS(bdbstorage.NewNS(enc), enc)
+	err := s.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	v, err := s.GetNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge1"), v)
+
+	v, err = s.GetNS([]byte("ns3"), []byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+
+	names, err := s.Namespaces()
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("ns1"), []byte("ns2")}, names)
+
+	var actual []string
+	err = s.RangeNS([]byte("ns2"), nil, nil, func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hoge=hoge2"}, actual)
+}
+
+func TestGetItemNS(t *testing.T) {
+	enc := nskey.Separator(":")
+	s := NewNS(bdbstorage.NewNS(enc), enc)
+	s.Load(sampleNSDBFile)
+
+	item, err := s.GetItemNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &storage.Item{Value: []byte("hoge1"), Flags: 0}, item)
+
+	item, err = s.GetItemNS([]byte("ns3"), []byte("hoge"))
+
+	assert.Nil(t, item)
+	assert.NotNil(t, err)
+}
@@ -10,7 +10,13 @@ import (
 )
 
 var (
-	_ storage.Storage = (*Storage)(nil)
+	_ storage.ItemStorage    = (*Storage)(nil)
+	_ storage.Iterable       = (*Storage)(nil)
+	_ storage.RangeIterable  = (*Storage)(nil)
+	_ storage.Generational   = (*Storage)(nil)
+	_ storage.MemoryReporter = (*Storage)(nil)
+	_ storage.StatsReporter  = (*Storage)(nil)
+	_ io.Closer              = (*Storage)(nil)
 )
 
 const _Zero uint8 = 0
@@ -30,6 +36,26 @@ func (s *Storage) Load(file string) error {
 	return s.proxy.Load(file)
 }
 
+// Generation returns the generation of proxy storage
+func (s *Storage) Generation() uint64 {
+	return storage.Generation(s.proxy)
+}
+
+// MemoryEstimate returns estimated bytes taken by proxy storage
+func (s *Storage) MemoryEstimate() uint64 {
+	return storage.MemoryEstimate(s.proxy)
+}
+
+// ReportStats calls fn with statistics of proxy storage
+func (s *Storage) ReportStats(fn func(string, uint64)) {
+	storage.ReportStats(s.proxy, fn)
+}
+
+// Close closes proxy storage
+func (s *Storage) Close() error {
+	return storage.Close(s.proxy)
+}
+
 // Get finds a given key in storage, deserialize its value into memcachedb format, and returns
 func (s *Storage) Get(key []byte) ([]byte, error) {
 	val, err := s.proxy.Get(key)
@@ -39,22 +65,73 @@ func (s *Storage) Get(key []byte) ([]byte, error) {
 	return unmarshalMemcachedbBytes(key, val)
 }
 
+// GetItem finds a given key in storage, deserialize its value and flags in memcachedb format, and returns
+func (s *Storage) GetItem(key []byte) (*storage.Item, error) {
+	val, err := s.proxy.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalMemcachedbItem(key, val)
+}
+
+// Keys finds keys in storage if it is iterable
+func (s *Storage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return storage.Keys(s.proxy, prefix, start, limit)
+}
+
+// Count counts keys in storage if it is iterable
+func (s *Storage) Count(prefix []byte) (int, error) {
+	return storage.Count(s.proxy, prefix)
+}
+
+// Range calls fn with items deserialized in memcachedb format having keys in a range in storage if it supports range
+func (s *Storage) Range(from, to []byte, fn func([]byte, *storage.Item) bool) error {
+	return storage.Range(s.proxy, from, to, unmarshalEach(fn, func(key []byte) []byte { return key }))
+}
+
+// unmarshalEach wraps fn to be called with items deserialized in memcachedb format, serialized with keys by fullKey.
+// An item failing deserialization is skipped, as Get does not find it either.
+func unmarshalEach(fn func([]byte, *storage.Item) bool, fullKey func([]byte) []byte) func([]byte, *storage.Item) bool {
+	return func(key []byte, item *storage.Item) bool {
+		decoded, err := unmarshalMemcachedbItem(fullKey(key), item.Value)
+		if err != nil {
+			return true
+		}
+		return fn(key, decoded)
+	}
+}
+
 func unmarshalMemcachedbBytes(key, b []byte) ([]byte, error) {
-	r := bytes.NewReader(b)
-	_, v, _, err := Deserialize(r)
+	item, err := unmarshalMemcachedbItem(key, b)
 	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+// unmarshalMemcachedbItem deserializes b, which must have been serialized with key
+func unmarshalMemcachedbItem(key, b []byte) (*storage.Item, error) {
+	r := bytes.NewReader(b)
+	k, item, err := DeserializeItem(r)
+	if err != nil || !bytes.Equal(k, key) {
 		return nil, storage.KeyNotFoundError(key)
 	}
-	return v, nil
+	return item, nil
 }
 
 // Serialize serializes given key and value into MemcacheDB format binary, and writes to writer
 func Serialize(w io.Writer, key, val []byte) error {
+	return SerializeItem(w, key, &storage.Item{Value: val})
+}
+
+// SerializeItem serializes given key, and value and flags of given item into MemcacheDB format binary, and writes to writer
+func SerializeItem(w io.Writer, key []byte, item *storage.Item) error {
+	val := item.Value
 	nKey := len(key)
 	nBytes := len(val) + 2
 
 	buf := new(bytes.Buffer)
-	fmt.Fprintf(buf, " %d %d\r\n", 0, len(val))
+	fmt.Fprintf(buf, " %d %d\r\n", item.Flags, len(val))
 
 	sSuffix := buf.Bytes()
 	nSuffix := len(sSuffix)
@@ -84,6 +161,15 @@ func Serialize(w io.Writer, key, val []byte) error {
 
 // Deserialize deserializes MemcacheDB format binary from reader into key, value and value length
 func Deserialize(r io.Reader) ([]byte, []byte, int, error) {
+	key, item, err := DeserializeItem(r)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return key, item.Value, len(item.Value), nil
+}
+
+// DeserializeItem deserializes MemcacheDB format binary from reader into key, and item with value and flags
+func DeserializeItem(r io.Reader) ([]byte, *storage.Item, error) {
 	var err error
 	var (
 		nBytes  int32
@@ -102,7 +188,7 @@ func Deserialize(r io.Reader) ([]byte, []byte, int, error) {
 	for _, v := range headers {
 		err = binary.Read(r, binary.LittleEndian, v)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("failed reading memcachedb binary headers: %s", err.Error())
+			return nil, nil, fmt.Errorf("failed reading memcachedb binary headers: %s", err.Error())
 		}
 	}
 
@@ -121,9 +207,14 @@ func Deserialize(r io.Reader) ([]byte, []byte, int, error) {
 	for _, v := range body {
 		err = binary.Read(r, binary.LittleEndian, v)
 		if err != nil {
-			return nil, nil, 0, fmt.Errorf("failed reading memcachedb binary body: %s", err.Error())
+			return nil, nil, fmt.Errorf("failed reading memcachedb binary body: %s", err.Error())
 		}
 	}
 
-	return key, val, int(nBytes - 2), nil
+	var flags uint32
+	if _, err = fmt.Sscanf(string(sSuffix), " %d ", &flags); err != nil {
+		return nil, nil, fmt.Errorf("failed reading memcachedb binary flags: %s", err.Error())
+	}
+
+	return key, &storage.Item{Value: val, Flags: flags}, nil
 }
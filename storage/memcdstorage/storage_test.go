@@ -1,10 +1,13 @@
 package memcdstorage
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
 	"github.com/yowcow/goromdb/storage/bdbstorage"
+	"github.com/yowcow/goromdb/storage/cdbstorage"
 )
 
 var sampleDBFile = "../../_data/store/sample-memcachedb-bdb.db"
@@ -99,3 +102,108 @@ func TestGet(t *testing.T) {
 		})
 	}
 }
+
+func TestGetItem(t *testing.T) {
+	p := bdbstorage.New()
+	s := New(p)
+	s.Load(sampleDBFile)
+
+	item, err := s.GetItem([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, &storage.Item{Value: []byte("hoge!"), Flags: 0}, item)
+
+	item, err = s.GetItem([]byte("hogefuga"))
+
+	assert.Nil(t, item)
+	assert.NotNil(t, err)
+}
+
+func TestSerializeItemAndDeserializeItem(t *testing.T) {
+	type Case struct {
+		input   *storage.Item
+		subtest string
+	}
+	cases := []Case{
+		{&storage.Item{Value: []byte("hoge!"), Flags: 0}, "zero flags"},
+		{&storage.Item{Value: []byte("hoge!"), Flags: 4294967295}, "max flags"},
+		{&storage.Item{Value: []byte(""), Flags: 123}, "empty value"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := SerializeItem(buf, []byte("hoge"), c.input)
+
+			assert.Nil(t, err)
+
+			key, item, err := DeserializeItem(buf)
+
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("hoge"), key)
+			assert.Equal(t, c.input, item)
+		})
+	}
+}
+
+func TestKeysAndCount(t *testing.T) {
+	s := New(bdbstorage.New())
+	s.Load(sampleDBFile)
+
+	keys, err := s.Keys([]byte("f"), nil, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("fuga")}, keys)
+
+	n, err := s.Count(nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+
+	s = New(cdbstorage.New())
+	_, err = s.Keys(nil, nil, 0)
+
+	assert.NotNil(t, err)
+
+	_, err = s.Count(nil)
+
+	assert.NotNil(t, err)
+}
+
+func TestRange(t *testing.T) {
+	s := New(bdbstorage.New())
+	s.Load(sampleDBFile)
+
+	var actual []string
+	err := s.Range([]byte("foo"), []byte("g"), func(key []byte, item *storage.Item) bool {
+		actual = append(actual, string(key)+"="+string(item.Value))
+		return true
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"foo=foo!!!", "fuga=fuga!!"}, actual)
+
+	s = New(cdbstorage.New())
+	err = s.Range(nil, nil, func(key []byte, item *storage.Item) bool {
+		return true
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestUnmarshalMemcachedbItemVerifiesKey(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := Serialize(buf, []byte("hoge"), []byte("hoge!"))
+
+	assert.Nil(t, err)
+
+	item, err := unmarshalMemcachedbItem([]byte("hoge"), buf.Bytes())
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge!"), item.Value)
+
+	item, err = unmarshalMemcachedbItem([]byte("ho"), buf.Bytes())
+
+	assert.Nil(t, item)
+	assert.True(t, storage.IsErrorKeyNotFound(err))
+}
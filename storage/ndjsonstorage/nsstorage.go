@@ -0,0 +1,24 @@
+package ndjsonstorage
+
+import (
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.NSStorage = (*NSStorage)(nil)
+)
+
+// NSStorage represents a namespaced NDJSON storage
+type NSStorage struct {
+	Storage
+}
+
+// NewNS creates and returns a namespaced storage
+func NewNS(gzipped bool) *NSStorage {
+	return &NSStorage{*New(gzipped)}
+}
+
+// GetNS finds a given key in given namespace, and returns its value
+func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	return s.get(ns, key)
+}
@@ -0,0 +1,71 @@
+package ndjsonstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+)
+
+func TestNewNS(t *testing.T) {
+	s := NewNS(false)
+	v, err := s.GetNS([]byte("ns1"), []byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestGetNS(t *testing.T) {
+	s := NewNS(true)
+	s.SetValueMode(jsonstorage.RawValues)
+	err := s.Load("valid.ndjson.gz")
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		input         [2][]byte
+		expectedVal   []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{
+			"namespace and key exists",
+			[2][]byte{[]byte("ns1"), []byte("hoge")},
+			[]byte("hoge1"),
+			false,
+		},
+		{
+			"namespace and key exists 2",
+			[2][]byte{[]byte("ns2"), []byte("number")},
+			[]byte("123"),
+			false,
+		},
+		{
+			"namespace exists but key not exists",
+			[2][]byte{[]byte("ns1"), []byte("fuga")},
+			nil,
+			true,
+		},
+		{
+			"namespace not exists",
+			[2][]byte{[]byte("ns3"), []byte("hoge")},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedVal, v)
+			assert.True(t, c.errorExpected == (err != nil))
+		})
+	}
+
+	v, err := s.Get([]byte("fuga"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fuga!!"), v)
+}
@@ -0,0 +1,171 @@
+package ndjsonstorage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+)
+
+var (
+	_ storage.Storage      = (*Storage)(nil)
+	_ storage.Generational = (*Storage)(nil)
+)
+
+// Data represents a key-value data
+type Data map[string][]byte
+
+// NSData represents a namespaced Data, where keys without namespace belong to namespace ""
+type NSData map[string]Data
+
+// Line represents a line in NDJSON data file
+type Line struct {
+	Key   *string         `json:"key"`
+	Value json.RawMessage `json:"value"`
+	NS    string          `json:"ns,omitempty"`
+}
+
+// Storage represents a NDJSON storage
+type Storage struct {
+	gzipped    bool
+	valueMode  jsonstorage.ValueMode
+	data       *atomic.Value
+	mux        *sync.RWMutex
+	generation *uint64
+}
+
+// New creates and returns a storage
+func New(gzipped bool) *Storage {
+	return &Storage{gzipped, jsonstorage.StringValues, new(atomic.Value), new(sync.RWMutex), new(uint64)}
+}
+
+// SetValueMode sets how values other than strings are handled.
+// This must be called before loading any data.
+func (s *Storage) SetValueMode(mode jsonstorage.ValueMode) {
+	s.valueMode = mode
+}
+
+// Load loads data into storage
+func (s *Storage) Load(file string) error {
+	data, err := s.openFile(file)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.data.Store(data)
+	atomic.AddUint64(s.generation, 1)
+	return nil
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+func (s Storage) openFile(file string) (NSData, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	r, err := s.newReader(fi)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.readLines(bufio.NewReader(r))
+}
+
+func (s Storage) newReader(rdr io.Reader) (io.Reader, error) {
+	if s.gzipped {
+		r, err := gzip.NewReader(rdr)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	return rdr, nil
+}
+
+func (s Storage) readLines(r *bufio.Reader) (NSData, error) {
+	nsdata := make(NSData)
+	for n := 1; ; n++ {
+		b, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("line %d: %s", n, err.Error())
+		}
+		if b = bytes.TrimSpace(b); len(b) > 0 {
+			if err := s.addLine(nsdata, b); err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err.Error())
+			}
+		}
+		if err == io.EOF {
+			return nsdata, nil
+		}
+	}
+}
+
+func (s Storage) addLine(nsdata NSData, b []byte) error {
+	var line Line
+	if err := json.Unmarshal(b, &line); err != nil {
+		return err
+	}
+	if line.Key == nil {
+		return fmt.Errorf("missing key")
+	}
+	if line.Value == nil {
+		return fmt.Errorf("missing value for key '%s'", *line.Key)
+	}
+
+	val, err := jsonstorage.DecodeValue(line.Value, s.valueMode)
+	if err != nil {
+		return fmt.Errorf("invalid value for key '%s': %s", *line.Key, err.Error())
+	}
+
+	data, ok := nsdata[line.NS]
+	if !ok {
+		data = make(Data)
+		nsdata[line.NS] = data
+	}
+	data[*line.Key] = append([]byte(nil), val...)
+	return nil
+}
+
+// Get finds a given key without namespace in data, and returns its value
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	return s.get(nil, key)
+}
+
+func (s *Storage) get(ns, key []byte) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ptr := s.data.Load()
+	if ptr == nil {
+		return nil, storage.KeyNotFoundError(key)
+	}
+
+	data, ok := ptr.(NSData)[string(ns)]
+	if !ok {
+		return nil, storage.KeyNotFoundError(key)
+	}
+
+	v, ok := data[string(key)]
+	if !ok {
+		return nil, storage.KeyNotFoundError(key)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,127 @@
+package ndjsonstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+)
+
+func TestNew(t *testing.T) {
+	s := New(false)
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	type Case struct {
+		gzipped       bool
+		valueMode     jsonstorage.ValueMode
+		input         string
+		expectedError string
+		subtest       string
+	}
+	cases := []Case{
+		{
+			false,
+			jsonstorage.StringValues,
+			"non-existing.ndjson",
+			"open non-existing.ndjson: no such file or directory",
+			"loading non-existing file fails",
+		},
+		{
+			false,
+			jsonstorage.StringValues,
+			"invalid.ndjson",
+			"line 3: unexpected end of JSON input",
+			"loading malformed line fails",
+		},
+		{
+			false,
+			jsonstorage.StringValues,
+			"missing-key.ndjson",
+			"line 2: missing key",
+			"loading line without key fails",
+		},
+		{
+			false,
+			jsonstorage.StringValues,
+			"valid.ndjson",
+			"line 6: invalid value for key 'number': expected a string but got 123",
+			"loading non-string value fails",
+		},
+		{
+			false,
+			jsonstorage.RawValues,
+			"valid.ndjson",
+			"",
+			"loading valid file succeeds",
+		},
+		{
+			true,
+			jsonstorage.RawValues,
+			"valid.ndjson.gz",
+			"",
+			"loading valid gzipped file succeeds",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := New(c.gzipped)
+			s.SetValueMode(c.valueMode)
+			err := s.Load(c.input)
+
+			if c.expectedError == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, c.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	s := New(false)
+	s.SetValueMode(jsonstorage.RawValues)
+	err := s.Load("valid.ndjson")
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		input       []byte
+		expectedVal []byte
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{
+			[]byte("hoge"),
+			[]byte("hoge!"),
+			false,
+			"existing key succeeds",
+		},
+		{
+			[]byte("number"),
+			nil,
+			true,
+			"key only in namespace fails",
+		},
+		{
+			[]byte("foobar"),
+			nil,
+			true,
+			"non-existing key fails",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.Get(c.input)
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expectedVal, v)
+		})
+	}
+}
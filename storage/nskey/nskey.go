@@ -0,0 +1,111 @@
+// Package nskey encodes a namespace and a key into a single key of a database without namespaces, and decodes it back.
+package nskey
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Encoding defines an interface to an encoding of a namespace and a key into a single key.
+// Keys in a namespace share the prefix of the namespace, and are in the same byte order as encoded.
+// Encode and Prefix fail for a namespace the encoding can't tell apart from others.
+type Encoding interface {
+	Encode(ns, key []byte) ([]byte, error)
+	Decode(fullKey []byte) (ns, key []byte, ok bool)
+	Prefix(ns []byte) ([]byte, error)
+}
+
+var (
+	_ Encoding = (*Separator)(nil)
+	_ Encoding = (*lengthPrefix)(nil)
+)
+
+// Separator represents an encoding putting a separator between a namespace and a key.
+// A namespace must not contain the separator.
+// An empty separator concatenates a namespace and a key, which can't be decoded.
+type Separator []byte
+
+// Concat defines an encoding concatenating a namespace and a key without anything between, as older versions did.
+// Namespace "ab" with key "c" and namespace "a" with key "bc" collide in it.
+var Concat = Separator(nil)
+
+// LengthPrefix defines an encoding putting the length of a namespace in uvarint before the namespace and a key,
+// which never collides
+var LengthPrefix Encoding = lengthPrefix{}
+
+// Encode encodes ns and key into ns+separator+key, and fails if ns contains the separator
+func (s Separator) Encode(ns, key []byte) ([]byte, error) {
+	if err := s.Validate(ns); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(ns)+len(s)+len(key))
+	buf = append(buf, ns...)
+	buf = append(buf, s...)
+	return append(buf, key...), nil
+}
+
+// Validate fails if ns contains the separator, which would make keys in ns decoded into another namespace
+func (s Separator) Validate(ns []byte) error {
+	if len(s) > 0 && bytes.Contains(ns, s) {
+		return fmt.Errorf("namespace '%s' contains separator '%s'", string(ns), string(s))
+	}
+	return nil
+}
+
+// Decode decodes fullKey into ns and key split at the first separator
+func (s Separator) Decode(fullKey []byte) ([]byte, []byte, bool) {
+	if len(s) == 0 {
+		return nil, nil, false
+	}
+	i := bytes.Index(fullKey, s)
+	if i < 0 {
+		return nil, nil, false
+	}
+	return fullKey[:i], fullKey[i+len(s):], true
+}
+
+// Prefix returns ns+separator, and fails if ns contains the separator
+func (s Separator) Prefix(ns []byte) ([]byte, error) {
+	return s.Encode(ns, nil)
+}
+
+type lengthPrefix struct{}
+
+// Encode encodes ns and key into uvarint(len(ns))+ns+key
+func (lengthPrefix) Encode(ns, key []byte) ([]byte, error) {
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(ns)+len(key))
+	buf = buf[:binary.PutUvarint(buf, uint64(len(ns)))]
+	buf = append(buf, ns...)
+	return append(buf, key...), nil
+}
+
+// Decode decodes fullKey into ns and key after reading length of ns
+func (lengthPrefix) Decode(fullKey []byte) ([]byte, []byte, bool) {
+	n, size := binary.Uvarint(fullKey)
+	if size <= 0 || uint64(len(fullKey)-size) < n {
+		return nil, nil, false
+	}
+	end := size + int(n)
+	return fullKey[size:end], fullKey[end:], true
+}
+
+// Prefix returns uvarint(len(ns))+ns
+func (l lengthPrefix) Prefix(ns []byte) ([]byte, error) {
+	return l.Encode(ns, nil)
+}
+
+// Parse parses an encoding named "concat", "length", or "separator=<separator>"
+func Parse(name string) (Encoding, error) {
+	switch {
+	case name == "concat":
+		return Concat, nil
+	case name == "length":
+		return LengthPrefix, nil
+	case strings.HasPrefix(name, "separator=") && len(name) > len("separator="):
+		return Separator(name[len("separator="):]), nil
+	default:
+		return nil, fmt.Errorf("don't know how to handle namespace key encoding '%s'", name)
+	}
+}
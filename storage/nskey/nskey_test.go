@@ -0,0 +1,135 @@
+package nskey
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeAndDecode(t *testing.T) {
+	type Case struct {
+		enc      Encoding
+		ns       []byte
+		key      []byte
+		expected []byte
+		subtest  string
+	}
+	cases := []Case{
+		{Separator(":"), []byte("ns"), []byte("key"), []byte("ns:key"), "separator"},
+		{Separator(":"), []byte("ns"), []byte("k:ey"), []byte("ns:k:ey"), "separator in key"},
+		{Separator(":"), []byte(""), []byte("key"), []byte(":key"), "separator with empty namespace"},
+		{LengthPrefix, []byte("ns"), []byte("key"), []byte("\x02nskey"), "length prefix"},
+		{LengthPrefix, bytes.Repeat([]byte("n"), 200), []byte("key"), append(append([]byte("\xc8\x01"), bytes.Repeat([]byte("n"), 200)...), "key"...), "length prefix in 2 bytes"},
+		{LengthPrefix, []byte(""), []byte(""), []byte("\x00"), "length prefix with empty namespace and key"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			fullKey, err := c.enc.Encode(c.ns, c.key)
+
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, fullKey)
+
+			prefix, err := c.enc.Prefix(c.ns)
+
+			assert.Nil(t, err)
+			assert.True(t, bytes.HasPrefix(fullKey, prefix))
+
+			ns, key, ok := c.enc.Decode(fullKey)
+
+			assert.True(t, ok)
+			assert.Equal(t, string(c.ns), string(ns))
+			assert.Equal(t, string(c.key), string(key))
+		})
+	}
+}
+
+func TestEncodingsNeverCollide(t *testing.T) {
+	encode := func(enc Encoding, ns, key string) []byte {
+		fullKey, err := enc.Encode([]byte(ns), []byte(key))
+
+		assert.Nil(t, err)
+		return fullKey
+	}
+
+	for _, enc := range []Encoding{Separator(":"), LengthPrefix} {
+		assert.NotEqual(t, encode(enc, "ab", "c"), encode(enc, "a", "bc"))
+	}
+	assert.Equal(t, encode(Concat, "ab", "c"), encode(Concat, "a", "bc"))
+}
+
+func TestEncodeFailsWithSeparatorInNamespace(t *testing.T) {
+	type Case struct {
+		enc         Encoding
+		ns          []byte
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{Separator(":"), []byte("a:b"), true, "separator in namespace fails"},
+		{Separator("::"), []byte("a:b"), false, "part of separator in namespace succeeds"},
+		{Concat, []byte("a:b"), false, "concat takes any namespace"},
+		{LengthPrefix, []byte("a:b"), false, "length prefix takes any namespace"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			_, err := c.enc.Encode(c.ns, []byte("key"))
+
+			assert.Equal(t, c.expectError, err != nil)
+
+			_, err = c.enc.Prefix(c.ns)
+
+			assert.Equal(t, c.expectError, err != nil)
+		})
+	}
+}
+
+func TestDecodeFails(t *testing.T) {
+	type Case struct {
+		enc     Encoding
+		input   []byte
+		subtest string
+	}
+	cases := []Case{
+		{Concat, []byte("hoge"), "concat can't be decoded"},
+		{Separator(":"), []byte("hoge"), "key without separator"},
+		{LengthPrefix, []byte("\x05hoge"), "namespace longer than key"},
+		{LengthPrefix, []byte(""), "empty key"},
+		{LengthPrefix, []byte("\xff"), "broken length"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			_, _, ok := c.enc.Decode(c.input)
+
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	type Case struct {
+		input       string
+		expected    Encoding
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{"concat", Concat, false, "concat"},
+		{"length", LengthPrefix, false, "length prefix"},
+		{"separator=::", Separator("::"), false, "separator"},
+		{"separator=", nil, true, "empty separator fails"},
+		{"hoge", nil, true, "unknown encoding fails"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			enc, err := Parse(c.input)
+
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expected, enc)
+		})
+	}
+}
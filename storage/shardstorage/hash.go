@@ -0,0 +1,49 @@
+package shardstorage
+
+import (
+	"fmt"
+	"hash/crc32"
+
+	"github.com/cespare/xxhash"
+)
+
+// Hash defines a function choosing a shard out of n shards for a key.
+// Data files must be sharded with the same function.
+type Hash func(key []byte, n int) int
+
+// CRC32 chooses a shard by CRC-32 (IEEE) checksum of a key modulo n
+func CRC32(key []byte, n int) int {
+	return int(crc32.ChecksumIEEE(key) % uint32(n))
+}
+
+// XXHash chooses a shard by 64-bit xxHash of a key modulo n
+func XXHash(key []byte, n int) int {
+	return int(xxhash.Sum64(key) % uint64(n))
+}
+
+// JumpHash chooses a shard by jump consistent hash of 64-bit xxHash of a key,
+// which moves only about 1/n of keys when a shard is added
+func JumpHash(key []byte, n int) int {
+	h := xxhash.Sum64(key)
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		h = h*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((h>>33)+1)))
+	}
+	return int(b)
+}
+
+// ParseHash parses a hash named "crc32", "xxhash", or "jump"
+func ParseHash(name string) (Hash, error) {
+	switch name {
+	case "crc32":
+		return CRC32, nil
+	case "xxhash":
+		return XXHash, nil
+	case "jump":
+		return JumpHash, nil
+	default:
+		return nil, fmt.Errorf("don't know how to handle shard hash '%s'", name)
+	}
+}
@@ -0,0 +1,60 @@
+package shardstorage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	type Case struct {
+		subtest string
+		hash    Hash
+	}
+	cases := []Case{
+		{"crc32", CRC32},
+		{"xxhash", XXHash},
+		{"jump", JumpHash},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			counts := make([]int, 4)
+			for i := 0; i < 1000; i++ {
+				key := []byte(fmt.Sprintf("key%d", i))
+				shard := c.hash(key, 4)
+
+				assert.True(t, shard >= 0 && shard < 4)
+				assert.Equal(t, shard, c.hash(key, 4))
+				counts[shard]++
+			}
+			for _, n := range counts {
+				assert.True(t, n > 150)
+			}
+		})
+	}
+}
+
+func TestJumpHashMovesKeysOnlyToNewShard(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key%d", i))
+		before := JumpHash(key, 4)
+		after := JumpHash(key, 5)
+
+		assert.True(t, after == before || after == 4)
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	for _, name := range []string{"crc32", "xxhash", "jump"} {
+		h, err := ParseHash(name)
+
+		assert.Nil(t, err)
+		assert.NotNil(t, h)
+	}
+
+	_, err := ParseHash("md5")
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,149 @@
+// Package shardstorage spreads keys over shards of a storage, each loaded from its own file in a shard set.
+package shardstorage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.Storage      = (*Storage)(nil)
+	_ storage.Generational = (*Storage)(nil)
+	_ io.Closer            = (*Storage)(nil)
+)
+
+// shardFilePattern matches a shard file like "data.shard-00.db", and not its sidecar like "data.shard-00.db.bloom"
+var shardFilePattern = regexp.MustCompile(`^.+\.shard-(\d+)(\.[^.]*)?$`)
+
+// Storage represents a storage finding a key in a shard chosen by hash.
+// Shards are loaded into a standby set of storages, and all shards switch to the new set together.
+type Storage struct {
+	shards     *atomic.Value
+	sets       [2][]storage.Storage
+	standby    int
+	hash       Hash
+	mux        *sync.RWMutex
+	generation *uint64
+}
+
+// New creates and returns a storage of n shards, with two sets of n storages created by newStorage
+func New(n int, hash Hash, newStorage func() storage.Storage) *Storage {
+	var sets [2][]storage.Storage
+	for i := range sets {
+		sets[i] = make([]storage.Storage, n)
+		for j := range sets[i] {
+			sets[i][j] = newStorage()
+		}
+	}
+	return &Storage{new(atomic.Value), sets, 0, hash, new(sync.RWMutex), new(uint64)}
+}
+
+// ShardFile returns a name of a shard file, like "data.shard-00.db" for "data.db"
+func ShardFile(file string, i int) string {
+	ext := filepath.Ext(file)
+	return fmt.Sprintf("%s.shard-%02d%s", file[:len(file)-len(ext)], i, ext)
+}
+
+// Load loads shard files in a directory into the standby set of storages, and switches all shards to the set.
+// A shard set not having exactly a file for each shard is rejected, and so is a set failing to load any shard.
+func (s *Storage) Load(dir string) error {
+	files, err := s.findShardFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	set := s.sets[s.standby]
+	for i, file := range files {
+		if err := set[i].Load(file); err != nil {
+			return fmt.Errorf("failed loading shard %d from '%s': %s", i, file, err.Error())
+		}
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.shards.Store(set)
+	s.standby = 1 - s.standby
+	atomic.AddUint64(s.generation, 1)
+	return nil
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+// Close closes storages of all shards in both sets, and returns the first error if any
+func (s *Storage) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.shards.Store([]storage.Storage(nil))
+	var first error
+	for _, set := range s.sets {
+		for _, stg := range set {
+			if err := storage.Close(stg); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// findShardFiles finds shard files in dir, and returns them ordered by shard
+func (s *Storage) findShardFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(s.sets[0])
+	files := make([]string, n)
+	found := 0
+	for _, fi := range entries {
+		m := shardFilePattern.FindStringSubmatch(fi.Name())
+		if m == nil {
+			continue
+		}
+		i, err := strconv.Atoi(m[1])
+		if err != nil || i >= n {
+			return nil, fmt.Errorf("shard file '%s' is out of %d shards", fi.Name(), n)
+		}
+		if files[i] != "" {
+			return nil, fmt.Errorf("shard %d has more than one file in '%s'", i, dir)
+		}
+		files[i] = filepath.Join(dir, fi.Name())
+		found++
+	}
+	if found != n {
+		return nil, fmt.Errorf("shard set in '%s' has %d shards but expected %d", dir, found, n)
+	}
+	return files, nil
+}
+
+func (s *Storage) getShards() []storage.Storage {
+	if ptr := s.shards.Load(); ptr != nil {
+		return ptr.([]storage.Storage)
+	}
+	return nil
+}
+
+// Get finds a given key in a shard chosen by hash, and returns its value
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	shards := s.getShards()
+	if shards == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+	return shards[s.hash(key, len(shards))].Get(key)
+}
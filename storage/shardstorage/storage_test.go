@@ -0,0 +1,191 @@
+package shardstorage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/storage"
+	"github.com/yowcow/goromdb/storage/jsonstorage"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+var sampleData = map[string]string{
+	"bar":  "bar!!!!",
+	"buz":  "buz!!!!!",
+	"foo":  "foo!!!",
+	"fuga": "fuga!!",
+	"hoge": "hoge!",
+}
+
+// writeShards writes data into n shard files in dir by hash, with suffix appended to each value
+func writeShards(dir string, n int, hash Hash, suffix string) {
+	shards := make([]map[string]string, n)
+	for i := range shards {
+		shards[i] = make(map[string]string)
+	}
+	for k, v := range sampleData {
+		shards[hash([]byte(k), n)][k] = v + suffix
+	}
+	for i, data := range shards {
+		b, _ := json.Marshal(data)
+		if err := ioutil.WriteFile(ShardFile(filepath.Join(dir, "data.json"), i), b, 0644); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func newJSONStorage() storage.Storage {
+	return jsonstorage.New(false)
+}
+
+func TestShardFile(t *testing.T) {
+	assert.Equal(t, "path/to/data.shard-00.db", ShardFile("path/to/data.db", 0))
+	assert.Equal(t, "path/to/data.shard-12", ShardFile("path/to/data", 12))
+}
+
+func TestLoadAndGet(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	writeShards(dir, 3, JumpHash, "")
+	ioutil.WriteFile(filepath.Join(dir, "data.shard-00.json.bloom"), nil, 0644)
+
+	s := New(3, JumpHash, newJSONStorage)
+
+	_, err := s.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+
+	err = s.Load(dir)
+
+	assert.Nil(t, err)
+
+	for k, v := range sampleData {
+		actual, err := s.Get([]byte(k))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(v), actual)
+	}
+
+	_, err = s.Get([]byte("piyo"))
+
+	assert.NotNil(t, err)
+}
+
+func TestLoadSwitchesAllShards(t *testing.T) {
+	s := New(2, CRC32, newJSONStorage)
+
+	for _, suffix := range []string{"1", "2", "3"} {
+		dir := testutil.CreateTmpDir()
+		writeShards(dir, 2, CRC32, suffix)
+		err := s.Load(dir)
+		os.RemoveAll(dir)
+
+		assert.Nil(t, err)
+
+		for k, v := range sampleData {
+			actual, err := s.Get([]byte(k))
+
+			assert.Nil(t, err)
+			assert.Equal(t, []byte(v+suffix), actual)
+		}
+	}
+
+	assert.Equal(t, uint64(3), s.Generation())
+}
+
+func TestLoadRejectsMismatchedShardSet(t *testing.T) {
+	good := testutil.CreateTmpDir()
+	defer os.RemoveAll(good)
+	writeShards(good, 2, CRC32, "")
+
+	type Case struct {
+		subtest string
+		prepare func(dir string)
+	}
+	cases := []Case{
+		{
+			"more shards than expected",
+			func(dir string) {
+				writeShards(dir, 3, CRC32, "!")
+			},
+		},
+		{
+			"less shards than expected",
+			func(dir string) {
+				writeShards(dir, 2, CRC32, "!")
+				os.Remove(filepath.Join(dir, "data.shard-01.json"))
+			},
+		},
+		{
+			"duplicate shard",
+			func(dir string) {
+				writeShards(dir, 2, CRC32, "!")
+				os.Rename(filepath.Join(dir, "data.shard-01.json"), filepath.Join(dir, "data.shard-00.db"))
+			},
+		},
+		{
+			"broken shard",
+			func(dir string) {
+				writeShards(dir, 2, CRC32, "!")
+				ioutil.WriteFile(filepath.Join(dir, "data.shard-01.json"), []byte("{"), 0644)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := New(2, CRC32, newJSONStorage)
+			assert.Nil(t, s.Load(good))
+
+			dir := testutil.CreateTmpDir()
+			defer os.RemoveAll(dir)
+			c.prepare(dir)
+
+			err := s.Load(dir)
+
+			assert.NotNil(t, err)
+
+			for k, v := range sampleData {
+				actual, err := s.Get([]byte(k))
+
+				assert.Nil(t, err)
+				assert.Equal(t, []byte(v), actual)
+			}
+		})
+	}
+}
+
+type closingStorage struct {
+	storage.Storage
+	closed *int
+}
+
+func (s closingStorage) Close() error {
+	*s.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	writeShards(dir, 2, CRC32, "")
+
+	closed := 0
+	s := New(2, CRC32, func() storage.Storage {
+		return closingStorage{jsonstorage.New(false), &closed}
+	})
+	s.Load(dir)
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, 4, closed)
+
+	_, err := s.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+}
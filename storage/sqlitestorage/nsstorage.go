@@ -0,0 +1,37 @@
+package sqlitestorage
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.NSStorage = (*NSStorage)(nil)
+)
+
+// NSStorage represents a namespaced SQLite storage
+type NSStorage struct {
+	Storage
+}
+
+// NewNS creates and returns a storage finding a value in valueCol by a namespace in nsCol and a key in keyCol of table
+func NewNS(table, nsCol, keyCol, valueCol string) *NSStorage {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ? AND %s = ? LIMIT 1",
+		quoteIdent(valueCol), quoteIdent(table), quoteIdent(nsCol), quoteIdent(keyCol),
+	)
+	return &NSStorage{Storage{new(atomic.Value), query, new(sync.RWMutex), new(uint64)}}
+}
+
+// Get always fails, since any key belongs to a namespace
+func (s *NSStorage) Get(key []byte) ([]byte, error) {
+	return nil, storage.KeyNotFoundError(key)
+}
+
+// GetNS finds a given key in given namespace, and returns its value
+func (s *NSStorage) GetNS(ns, key []byte) ([]byte, error) {
+	return s.get(key, string(ns), string(key))
+}
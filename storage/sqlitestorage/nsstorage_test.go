@@ -0,0 +1,75 @@
+package sqlitestorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleNSDBFile = "../../_data/store/sample-ns-sqlite.db"
+
+func TestNewNS(t *testing.T) {
+	NewNS("goromdb", "ns", "key", "value")
+}
+
+func TestGetNS(t *testing.T) {
+	s := NewNS("goromdb", "ns", "key", "value")
+	err := s.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	type Case struct {
+		subtest       string
+		input         [2][]byte
+		expectedValue []byte
+		errorExpected bool
+	}
+	cases := []Case{
+		{
+			"namespace and key exists",
+			[2][]byte{[]byte("ns1"), []byte("hoge")},
+			[]byte("hoge1"),
+			false,
+		},
+		{
+			"namespace exists but key not exists",
+			[2][]byte{[]byte("ns2"), []byte("fuga")},
+			nil,
+			true,
+		},
+		{
+			"namespace and key not exist",
+			[2][]byte{[]byte("foo"), []byte("bar")},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.GetNS(c.input[0], c.input[1])
+
+			assert.Equal(t, c.expectedValue, v)
+			assert.True(t, c.errorExpected == (err != nil))
+		})
+	}
+}
+
+func TestGetNSWithoutNamespaceFails(t *testing.T) {
+	s := NewNS("goromdb", "ns", "key", "value")
+	err := s.Load(sampleNSDBFile)
+
+	assert.Nil(t, err)
+
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+}
+
+func TestLoadNSWithoutNamespaceColumnFails(t *testing.T) {
+	s := NewNS("goromdb", "ns", "key", "value")
+	err := s.Load(sampleDBFile)
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,149 @@
+package sqlitestorage
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	// Registering sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/yowcow/goromdb/storage"
+)
+
+var (
+	_ storage.Storage      = (*Storage)(nil)
+	_ storage.Generational = (*Storage)(nil)
+	_ io.Closer            = (*Storage)(nil)
+)
+
+// Storage represents a SQLite storage
+type Storage struct {
+	db         *atomic.Value
+	query      string
+	mux        *sync.RWMutex
+	generation *uint64
+}
+
+// handle represents a connection pool and a prepared statement on it, to be swapped together
+type handle struct {
+	db   *sql.DB
+	stmt *sql.Stmt
+}
+
+// New creates and returns a storage finding a value in valueCol by a key in keyCol of table
+func New(table, keyCol, valueCol string) *Storage {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s = ? LIMIT 1",
+		quoteIdent(valueCol), quoteIdent(table), quoteIdent(keyCol),
+	)
+	return &Storage{new(atomic.Value), query, new(sync.RWMutex), new(uint64)}
+}
+
+// quoteIdent quotes a SQL identifier.
+// Backquotes are used, since SQLite takes a double-quoted name of a non-existing column as a string literal.
+func quoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// Load opens a new connection pool into storage, and closes old connection pool if exists
+func (s *Storage) Load(file string) error {
+	newHandle, err := openDB(file, s.query)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldHandle := s.getHandle()
+	s.db.Store(newHandle)
+	atomic.AddUint64(s.generation, 1)
+	if oldHandle != nil {
+		oldHandle.close()
+	}
+	return nil
+}
+
+// Generation returns the number of times data has been loaded, which changes together with data being served
+func (s *Storage) Generation() uint64 {
+	return atomic.LoadUint64(s.generation)
+}
+
+// Close waits for reads in progress, and closes connection pool if exists
+func (s *Storage) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	oldHandle := s.getHandle()
+	if oldHandle == nil {
+		return nil
+	}
+	s.db.Store((*handle)(nil))
+	return oldHandle.close()
+}
+
+func (s *Storage) getHandle() *handle {
+	if ptr := s.db.Load(); ptr != nil {
+		return ptr.(*handle)
+	}
+	return nil
+}
+
+func openDB(file, query string) (*handle, error) {
+	db, err := sql.Open("sqlite3", readOnlyDSN(file))
+	if err != nil {
+		return nil, err
+	}
+	// Preparing a statement makes sure that file is a database having expected table and columns
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &handle{db, stmt}, nil
+}
+
+// readOnlyDSN returns a URI opening file read-only, escaping characters like "?", "#", and "%" in file
+func readOnlyDSN(file string) string {
+	u := &url.URL{Scheme: "file", Opaque: (&url.URL{Path: file}).EscapedPath(), RawQuery: "mode=ro"}
+	return u.String()
+}
+
+func (h *handle) close() error {
+	h.stmt.Close()
+	return h.db.Close()
+}
+
+// Get finds a given key in db, and returns its value.
+// Key is bound as text, so that it matches a key in TEXT column.
+func (s *Storage) Get(key []byte) ([]byte, error) {
+	return s.get(key, string(key))
+}
+
+func (s *Storage) get(key []byte, args ...interface{}) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	h := s.getHandle()
+	if h == nil {
+		return nil, storage.InternalError("couldn't load db")
+	}
+
+	var v []byte
+	err := h.stmt.QueryRow(args...).Scan(&v)
+	if err == sql.ErrNoRows {
+		return nil, storage.KeyNotFoundError(key)
+	} else if err != nil {
+		return nil, storage.InternalError(err.Error())
+	}
+	if v == nil {
+		// NULL value is served as an empty value
+		v = []byte{}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,168 @@
+package sqlitestorage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yowcow/goromdb/testutil"
+)
+
+var sampleDBFile = "../../_data/store/sample-sqlite.db"
+
+func TestNew(t *testing.T) {
+	New("goromdb", "key", "value")
+}
+
+func TestQuoteIdent(t *testing.T) {
+	assert.Equal(t, "`hoge`", quoteIdent("hoge"))
+	assert.Equal(t, "`ho``ge`", quoteIdent("ho`ge"))
+}
+
+func TestLoad(t *testing.T) {
+	type Case struct {
+		input       string
+		table       string
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{
+			"./",
+			"goromdb",
+			true,
+			"loading directory fails",
+		},
+		{
+			sampleDBFile + ".hoge",
+			"goromdb",
+			true,
+			"loading non-existing file fails",
+		},
+		{
+			"../../_data/sample-data.json",
+			"goromdb",
+			true,
+			"loading non-sqlite file fails",
+		},
+		{
+			sampleDBFile,
+			"hoge",
+			true,
+			"loading file without table fails",
+		},
+		{
+			sampleDBFile,
+			"goromdb",
+			false,
+			"loading valid file succeeds",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			s := New(c.table, "key", "value")
+			err := s.Load(c.input)
+			assert.Equal(t, c.expectError, err != nil)
+		})
+	}
+}
+
+func TestLoadFileWithURICharacters(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "data?mode=rw#1%20.db")
+	testutil.CopyFile(file, sampleDBFile)
+
+	s := New("goromdb", "key", "value")
+	err := s.Load(file)
+
+	assert.Nil(t, err)
+
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge!"), v)
+}
+
+func TestReadOnlyDSN(t *testing.T) {
+	assert.Equal(t, "file:/tmp/a%3Fb%23c%25d%20e.db?mode=ro", readOnlyDSN("/tmp/a?b#c%d e.db"))
+	assert.Equal(t, "file:data.db?mode=ro", readOnlyDSN("data.db"))
+}
+
+func TestLoadClosesOldHandle(t *testing.T) {
+	s := New("goromdb", "key", "value")
+
+	assert.Nil(t, s.Load(sampleDBFile))
+
+	oldHandle := s.getHandle()
+
+	assert.Nil(t, s.Load(sampleDBFile))
+	assert.NotNil(t, oldHandle.db.Ping())
+
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hoge!"), v)
+}
+
+func TestClose(t *testing.T) {
+	s := New("goromdb", "key", "value")
+
+	assert.Nil(t, s.Close())
+
+	s.Load(sampleDBFile)
+
+	assert.Nil(t, s.Close())
+
+	_, err := s.Get([]byte("hoge"))
+
+	assert.NotNil(t, err)
+	assert.Nil(t, s.Load(sampleDBFile))
+
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, err)
+	assert.NotNil(t, v)
+}
+
+func TestGet(t *testing.T) {
+	s := New("goromdb", "key", "value")
+	v, err := s.Get([]byte("hoge"))
+
+	assert.Nil(t, v)
+	assert.NotNil(t, err)
+
+	s.Load(sampleDBFile)
+
+	type Case struct {
+		input       []byte
+		expected    []byte
+		expectError bool
+		subtest     string
+	}
+	cases := []Case{
+		{
+			[]byte("hoge"),
+			[]byte("hoge!"),
+			false,
+			"existing key returns expected val",
+		},
+		{
+			[]byte("hogehoge"),
+			nil,
+			true,
+			"non-existing key returns error",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.subtest, func(t *testing.T) {
+			v, err := s.Get(c.input)
+			assert.Equal(t, c.expectError, err != nil)
+			assert.Equal(t, c.expected, v)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"io"
 )
 
 // Storage defines an interface to a storage
@@ -16,6 +17,167 @@ type NSStorage interface {
 	GetNS(namespace, key []byte) ([]byte, error)
 }
 
+// Item represents a value with its metadata
+type Item struct {
+	Value []byte
+	Flags uint32
+	CAS   uint64
+}
+
+// ItemStorage defines an interface to a storage serving values with metadata
+type ItemStorage interface {
+	Storage
+	GetItem(key []byte) (*Item, error)
+}
+
+// NSItemStorage defines an interface to a namespaced storage serving values with metadata
+type NSItemStorage interface {
+	NSStorage
+	GetItemNS(namespace, key []byte) (*Item, error)
+}
+
+// Iterable defines an interface to a storage enumerating its keys.
+// Keys returns up to limit keys (no limit if limit <= 0) having prefix, in byte order, starting at start.
+type Iterable interface {
+	Keys(prefix, start []byte, limit int) ([][]byte, error)
+	Count(prefix []byte) (int, error)
+}
+
+// NSIterable defines an interface to a namespaced storage enumerating its namespaces and keys in them
+type NSIterable interface {
+	Namespaces() ([][]byte, error)
+	KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error)
+	CountNS(ns, prefix []byte) (int, error)
+}
+
+// RangeIterable defines an interface to a storage iterating items in byte order of keys.
+// Range calls fn with items having keys from "from" to "to" inclusive (no upper bound if to is empty), until fn returns false.
+type RangeIterable interface {
+	Range(from, to []byte, fn func(key []byte, item *Item) bool) error
+}
+
+// NSRangeIterable defines an interface to a namespaced storage iterating items in a namespace in byte order of keys
+type NSRangeIterable interface {
+	RangeNS(ns, from, to []byte, fn func(key []byte, item *Item) bool) error
+}
+
+// Generational defines an interface to a storage counting loads of data by itself.
+// Generation changes together with data being served, so that a lookup between two equal generations reads data of that generation.
+type Generational interface {
+	Generation() uint64
+}
+
+// Generation returns the generation of s if it counts loads by itself, or otherwise 0
+func Generation(s Storage) uint64 {
+	if g, ok := s.(Generational); ok {
+		return g.Generation()
+	}
+	return 0
+}
+
+// GetItemNS finds a given ns+key in s, and returns its value with metadata if s serves any
+func GetItemNS(s NSStorage, ns, key []byte) (*Item, error) {
+	if is, ok := s.(NSItemStorage); ok {
+		return is.GetItemNS(ns, key)
+	}
+	v, err := s.GetNS(ns, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Item{Value: v}, nil
+}
+
+// Keys returns up to limit keys having prefix in s starting at start, if s is iterable
+func Keys(s Storage, prefix, start []byte, limit int) ([][]byte, error) {
+	if it, ok := s.(Iterable); ok {
+		return it.Keys(prefix, start, limit)
+	}
+	return nil, NotIterableError()
+}
+
+// Count counts keys having prefix in s, if s is iterable
+func Count(s Storage, prefix []byte) (int, error) {
+	if it, ok := s.(Iterable); ok {
+		return it.Count(prefix)
+	}
+	return 0, NotIterableError()
+}
+
+// Range calls fn with items in a range of keys in s, if s supports range
+func Range(s Storage, from, to []byte, fn func(key []byte, item *Item) bool) error {
+	if it, ok := s.(RangeIterable); ok {
+		return it.Range(from, to, fn)
+	}
+	return NotIterableError()
+}
+
+// Namespaces returns namespaces in s, if s is iterable by namespace
+func Namespaces(s Storage) ([][]byte, error) {
+	if it, ok := s.(NSIterable); ok {
+		return it.Namespaces()
+	}
+	return nil, NotIterableError()
+}
+
+// KeysNS returns up to limit keys having prefix in namespace in s starting at start, if s is iterable by namespace
+func KeysNS(s Storage, ns, prefix, start []byte, limit int) ([][]byte, error) {
+	if it, ok := s.(NSIterable); ok {
+		return it.KeysNS(ns, prefix, start, limit)
+	}
+	return nil, NotIterableError()
+}
+
+// CountNS counts keys having prefix in namespace in s, if s is iterable by namespace
+func CountNS(s Storage, ns, prefix []byte) (int, error) {
+	if it, ok := s.(NSIterable); ok {
+		return it.CountNS(ns, prefix)
+	}
+	return 0, NotIterableError()
+}
+
+// RangeNS calls fn with items in a range of keys in namespace in s, if s supports range by namespace
+func RangeNS(s Storage, ns, from, to []byte, fn func(key []byte, item *Item) bool) error {
+	if it, ok := s.(NSRangeIterable); ok {
+		return it.RangeNS(ns, from, to, fn)
+	}
+	return NotIterableError()
+}
+
+// MemoryReporter defines an interface to a storage reporting estimated bytes taken by loaded data
+type MemoryReporter interface {
+	MemoryEstimate() uint64
+}
+
+// MemoryEstimate returns estimated bytes taken by s if it reports them, or otherwise 0
+func MemoryEstimate(s Storage) uint64 {
+	if r, ok := s.(MemoryReporter); ok {
+		return r.MemoryEstimate()
+	}
+	return 0
+}
+
+// StatsReporter defines an interface to a storage reporting its statistics, like hits of cache.
+// ReportStats calls fn with each statistic by name in fixed order.
+type StatsReporter interface {
+	ReportStats(fn func(name string, value uint64))
+}
+
+// ReportStats calls fn with statistics of s if it reports any
+func ReportStats(s Storage, fn func(name string, value uint64)) {
+	if r, ok := s.(StatsReporter); ok {
+		r.ReportStats(fn)
+	}
+}
+
+// Close closes s if it holds resources such as file handles, by io.Closer.
+// A closed storage serves no data until loaded again.
+func Close(s Storage) error {
+	if c, ok := s.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // ErrorBucketNotFound bucket-not-found error type
 type ErrorBucketNotFound struct {
 	error
@@ -52,6 +214,11 @@ func InternalError(s string) error {
 	}
 }
 
+// NotIterableError returns an error for a storage not enumerating its keys
+func NotIterableError() error {
+	return InternalError("storage is not iterable")
+}
+
 // IsErrorBucketNotFound returns if it is an ErrorBucketNotFound
 func IsErrorBucketNotFound(err error) bool {
 	switch err.(type) {
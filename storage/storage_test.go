@@ -92,3 +92,56 @@ func TestIsErrorBuckettNotFound(t *testing.T) {
 		})
 	}
 }
+
+// plainStorage represents a storage enumerating nothing
+type plainStorage struct{}
+
+func (plainStorage) Get(key []byte) ([]byte, error) { return nil, KeyNotFoundError(key) }
+func (plainStorage) Load(file string) error         { return nil }
+
+// iterableStorage represents a storage having a key in a namespace
+type iterableStorage struct {
+	plainStorage
+}
+
+func (iterableStorage) Keys(prefix, start []byte, limit int) ([][]byte, error) {
+	return [][]byte{[]byte("key")}, nil
+}
+func (iterableStorage) Count(prefix []byte) (int, error) { return 1, nil }
+func (iterableStorage) Range(from, to []byte, fn func([]byte, *Item) bool) error {
+	fn([]byte("key"), &Item{Value: []byte("value")})
+	return nil
+}
+func (iterableStorage) Namespaces() ([][]byte, error) { return [][]byte{[]byte("ns")}, nil }
+func (iterableStorage) KeysNS(ns, prefix, start []byte, limit int) ([][]byte, error) {
+	return [][]byte{[]byte("key")}, nil
+}
+func (iterableStorage) CountNS(ns, prefix []byte) (int, error) { return 1, nil }
+func (iterableStorage) RangeNS(ns, from, to []byte, fn func([]byte, *Item) bool) error {
+	fn([]byte("key"), &Item{Value: []byte("value")})
+	return nil
+}
+
+func TestIterableHelpers(t *testing.T) {
+	fn := func([]byte, *Item) bool { return true }
+	calls := map[string]func(Storage) error{
+		"Keys":       func(s Storage) error { _, err := Keys(s, nil, nil, 0); return err },
+		"Count":      func(s Storage) error { _, err := Count(s, nil); return err },
+		"Range":      func(s Storage) error { return Range(s, nil, nil, fn) },
+		"Namespaces": func(s Storage) error { _, err := Namespaces(s); return err },
+		"KeysNS":     func(s Storage) error { _, err := KeysNS(s, nil, nil, nil, 0); return err },
+		"CountNS":    func(s Storage) error { _, err := CountNS(s, nil, nil); return err },
+		"RangeNS":    func(s Storage) error { return RangeNS(s, nil, nil, nil, fn) },
+	}
+
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			if err := call(iterableStorage{}); err != nil {
+				t.Fatalf("want no error from iterable storage, got %v", err)
+			}
+			if err := call(plainStorage{}); err == nil {
+				t.Fatal("want error from storage not iterable, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+var (
+	_ Watcher = (*DirWatcher)(nil)
+)
+
+// DirWatcher represents a watcher for a directory, like a LevelDB database.
+// A directory is expected to be completely written elsewhere, and renamed into the watched path.
+type DirWatcher struct {
+	dir      string
+	interval int
+	logger   *log.Logger
+}
+
+// NewDirWatcher returns a DirWatcher
+func NewDirWatcher(dir string, interval int, logger *log.Logger) *DirWatcher {
+	return &DirWatcher{dir, interval, logger}
+}
+
+// Start starts a watcher goroutine, and returns a channel that emits a directory path
+func (w *DirWatcher) Start(ctx context.Context) <-chan string {
+	out := make(chan string)
+	go w.watch(ctx, out)
+	return out
+}
+
+func (w *DirWatcher) watch(ctx context.Context, out chan<- string) {
+	d := time.Duration(w.interval) * time.Millisecond
+	tc := time.NewTicker(d)
+	defer func() {
+		w.logger.Printf("dirwatcher finished watching for directory: %s", w.dir)
+		tc.Stop()
+		close(out)
+	}()
+	w.logger.Printf("dirwatcher started watching for directory: %s", w.dir)
+	for {
+		select {
+		case <-tc.C:
+			if ok, err := verifyDir(w.dir); ok {
+				out <- w.dir
+			} else if err != nil {
+				w.logger.Println("dirwatcher directory verification failed:", err.Error())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func verifyDir(dir string) (bool, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return false, nil
+	}
+
+	if !fi.IsDir() {
+		return false, fmt.Errorf("expected a directory but got a file for %s", dir)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/yowcow/goromdb/testutil"
+)
+
+func TestDirWatcherVerifyFailsWhenFileIsGiven(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	var logbuf bytes.Buffer
+	logger := log.New(&logbuf, "", 0)
+	file := filepath.Join(dir, "hoge.db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewDirWatcher(file, 10, logger)
+	out := w.Start(ctx)
+
+	testutil.CopyFile(file, "valid.txt")
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	<-out
+
+	re := regexp.MustCompile("expected a directory but got a file")
+	if re.MatchString(logbuf.String()) != true {
+		t.Error("'expected a directory but got ...' but got", logbuf.String())
+	}
+}
+
+func TestDirWatcherVerifySucceeds(t *testing.T) {
+	dir := testutil.CreateTmpDir()
+	defer os.RemoveAll(dir)
+
+	var logbuf bytes.Buffer
+	logger := log.New(&logbuf, "", 0)
+	file := filepath.Join(dir, "hoge.db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewDirWatcher(file, 10, logger)
+	out := w.Start(ctx)
+
+	if err := os.Mkdir(file, 0755); err != nil {
+		t.Fatal("failed creating a dir", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	fileOut := <-out
+
+	cancel() // should close chan
+	<-out
+
+	if fileOut != file {
+		t.Error("expected", file, "but got", fileOut)
+	}
+}